@@ -0,0 +1,186 @@
+package errguard
+
+import (
+	"fmt"
+	"log"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-stack/stack"
+)
+
+// StdLogger is a Logger that writes messages to a standard
+// library logger, formatted as logfmt key/value pairs.
+//
+// If the logger's flags include log.Lshortfile or log.Llongfile, the
+// file and line are those of the message's caller, which is the code
+// that called the guard. Messages without a caller, such as circuit
+// breaker state changes, report a file and line in this package.
+type StdLogger struct {
+	// Logger is the destination. If nil, log.Default() is used.
+	Logger *log.Logger
+}
+
+// NewStdLogger returns a logger that writes messages to l.
+func NewStdLogger(l *log.Logger) *StdLogger {
+	return &StdLogger{Logger: l}
+}
+
+// Log implements the Logger interface.
+func (l *StdLogger) Log(v ...interface{}) error {
+	logger := l.Logger
+	if logger == nil {
+		logger = log.Default()
+	}
+	calldepth := 2
+	var sb strings.Builder
+	for i := 0; i < len(v); i += 2 {
+		if i > 0 {
+			sb.WriteByte(' ')
+		}
+		sb.WriteString(logfmtValue(fmt.Sprint(v[i])))
+		sb.WriteByte('=')
+		if i+1 < len(v) {
+			if call, ok := v[i+1].(stack.Call); ok {
+				if depth, ok := callDepth(call); ok {
+					calldepth = depth
+				}
+			}
+			sb.WriteString(logfmtValue(fmt.Sprint(formatValue(v[i+1]))))
+		}
+	}
+	return logger.Output(calldepth, sb.String())
+}
+
+// callDepth returns the call depth of call relative to the caller
+// of callDepth, as expected by log.Logger.Output when called from
+// the same function. It reports false if call is not in the stack.
+func callDepth(call stack.Call) (int, bool) {
+	pc := call.Frame().PC
+	var pcs [64]uintptr
+	frames := runtime.CallersFrames(pcs[:runtime.Callers(2, pcs[:])])
+	for depth := 1; ; depth++ {
+		frame, more := frames.Next()
+		if frame.PC == pc {
+			return depth, true
+		}
+		if !more {
+			return 0, false
+		}
+	}
+}
+
+// KitLogger is a Logger that writes messages to a go-kit style logger.
+// Such loggers already satisfy the Logger interface, but KitLogger
+// formats the values logged by the guard, such as the caller and
+// errors, the same way as the other adapters.
+type KitLogger struct {
+	Logger interface {
+		Log(keyvals ...interface{}) error
+	}
+}
+
+// NewKitLogger returns a logger that writes messages to l.
+func NewKitLogger(l interface {
+	Log(keyvals ...interface{}) error
+}) *KitLogger {
+	return &KitLogger{Logger: l}
+}
+
+// Log implements the Logger interface.
+func (l *KitLogger) Log(v ...interface{}) error {
+	keyvals := make([]interface{}, len(v))
+	for i, value := range v {
+		if i%2 == 1 {
+			value = formatValue(value)
+		}
+		keyvals[i] = value
+	}
+	return l.Logger.Log(keyvals...)
+}
+
+// Sugared is the interface of a sugared logger, such as the
+// SugaredLogger type in go.uber.org/zap.
+type Sugared interface {
+	Debugw(msg string, keysAndValues ...interface{})
+	Infow(msg string, keysAndValues ...interface{})
+	Warnw(msg string, keysAndValues ...interface{})
+	Errorw(msg string, keysAndValues ...interface{})
+}
+
+// SugaredLogger is a Logger that writes messages to a sugared logger.
+// The "level" value logged by the guard determines the method called,
+// and the "msg" value becomes the message.
+type SugaredLogger struct {
+	Logger Sugared
+}
+
+// NewSugaredLogger returns a logger that writes messages to l.
+func NewSugaredLogger(l Sugared) *SugaredLogger {
+	return &SugaredLogger{Logger: l}
+}
+
+// Log implements the Logger interface.
+func (l *SugaredLogger) Log(v ...interface{}) error {
+	level, msg, keyvals := splitKeyvals(v)
+	for i := 1; i < len(keyvals); i += 2 {
+		keyvals[i] = formatValue(keyvals[i])
+	}
+	switch level {
+	case "debug":
+		l.Logger.Debugw(msg, keyvals...)
+	case "warn", "warning":
+		l.Logger.Warnw(msg, keyvals...)
+	case "error":
+		l.Logger.Errorw(msg, keyvals...)
+	default:
+		l.Logger.Infow(msg, keyvals...)
+	}
+	return nil
+}
+
+// splitKeyvals returns the "level" and "msg" values from the
+// key/value pairs in v, and the remaining key/value pairs.
+func splitKeyvals(v []interface{}) (level string, msg string, keyvals []interface{}) {
+	for i := 0; i < len(v); i += 2 {
+		key := fmt.Sprint(v[i])
+		var value interface{}
+		if i+1 < len(v) {
+			value = v[i+1]
+		}
+		switch key {
+		case "level":
+			level = fmt.Sprint(value)
+		case "msg":
+			msg = fmt.Sprint(value)
+		default:
+			keyvals = append(keyvals, key, value)
+		}
+	}
+	return level, msg, keyvals
+}
+
+// formatValue converts values logged by the guard into
+// a consistent form for the logger adapters.
+func formatValue(value interface{}) interface{} {
+	switch v := value.(type) {
+	case stack.Call:
+		// file name and line number
+		return fmt.Sprint(v)
+	case error:
+		return v.Error()
+	case time.Duration:
+		return v.String()
+	}
+	return value
+}
+
+// logfmtValue quotes s if necessary for logfmt output.
+func logfmtValue(s string) string {
+	if s == "" || strings.ContainsAny(s, " =\"\t\r\n") {
+		return strconv.Quote(s)
+	}
+	return s
+}
@@ -0,0 +1,113 @@
+package errguard
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"reflect"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-stack/stack"
+)
+
+func TestStdLogger(t *testing.T) {
+	var buf bytes.Buffer
+	logger := NewStdLogger(log.New(&buf, "", 0))
+	caller := stack.Caller(0)
+	logger.Log(
+		"level", "warn",
+		"msg", "retrying",
+		"error", errors.New("deadlock detected"),
+		"caller", caller,
+		"attempt", 2,
+		"delay", 200*time.Millisecond,
+		"empty", "",
+	)
+	want := fmt.Sprintf(`level=warn msg=retrying error="deadlock detected" caller=%v attempt=2 delay=200ms empty=""`, caller)
+	if got := strings.TrimSpace(buf.String()); got != want {
+		t.Errorf("got=%q\nwant=%q", got, want)
+	}
+}
+
+func TestStdLoggerFileLine(t *testing.T) {
+	var buf bytes.Buffer
+	guard := Guard{
+		Backoff:     ConstantBackoff{Interval: time.Millisecond},
+		MaxAttempts: 2,
+		Logger:      NewStdLogger(log.New(&buf, "", log.Lshortfile)),
+	}
+	_, _, line, _ := runtime.Caller(0)
+	guard.Run(context.Background(), func() error {
+		return Retry(errors.New("test error"))
+	})
+	want := fmt.Sprintf("adapters_test.go:%d: ", line+1)
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if got, want := len(lines), 2; got != want {
+		t.Fatalf("got=%v, want=%v\n%s", got, want, buf.String())
+	}
+	for i, l := range lines {
+		if !strings.HasPrefix(l, want) {
+			t.Errorf("%d: got=%q, want prefix %q", i, l, want)
+		}
+	}
+}
+
+func TestKitLogger(t *testing.T) {
+	var got []interface{}
+	logger := NewKitLogger(loggerFunc(func(keyvals ...interface{}) error {
+		got = keyvals
+		return nil
+	}))
+	caller := stack.Caller(0)
+	logger.Log("level", "info", "error", errors.New("test error"), "caller", caller, "attempt", 1)
+	want := []interface{}{"level", "info", "error", "test error", "caller", fmt.Sprint(caller), "attempt", 1}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got=%v, want=%v", got, want)
+	}
+}
+
+type sugaredCall struct {
+	method        string
+	msg           string
+	keysAndValues []interface{}
+}
+
+type fakeSugared struct {
+	calls []sugaredCall
+}
+
+func (s *fakeSugared) Debugw(msg string, kv ...interface{}) { s.record("debug", msg, kv) }
+func (s *fakeSugared) Infow(msg string, kv ...interface{})  { s.record("info", msg, kv) }
+func (s *fakeSugared) Warnw(msg string, kv ...interface{})  { s.record("warn", msg, kv) }
+func (s *fakeSugared) Errorw(msg string, kv ...interface{}) { s.record("error", msg, kv) }
+
+func (s *fakeSugared) record(method string, msg string, kv []interface{}) {
+	s.calls = append(s.calls, sugaredCall{method: method, msg: msg, keysAndValues: kv})
+}
+
+func TestSugaredLogger(t *testing.T) {
+	sugared := &fakeSugared{}
+	logger := NewSugaredLogger(sugared)
+	caller := stack.Caller(0)
+	logger.Log("level", "debug", "msg", "one")
+	logger.Log("level", "info", "msg", "two", "attempt", 1)
+	logger.Log("level", "warn", "msg", "three", "caller", caller)
+	logger.Log("level", "error", "msg", "four", "error", errors.New("test error"))
+	logger.Log("msg", "five")
+
+	want := []sugaredCall{
+		{method: "debug", msg: "one"},
+		{method: "info", msg: "two", keysAndValues: []interface{}{"attempt", 1}},
+		{method: "warn", msg: "three", keysAndValues: []interface{}{"caller", fmt.Sprint(caller)}},
+		{method: "error", msg: "four", keysAndValues: []interface{}{"error", "test error"}},
+		{method: "info", msg: "five"},
+	}
+	if !reflect.DeepEqual(sugared.calls, want) {
+		t.Errorf("got=%v\nwant=%v", sugared.calls, want)
+	}
+}
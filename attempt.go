@@ -0,0 +1,58 @@
+package errguard
+
+import (
+	"context"
+	"errors"
+)
+
+// AttemptInfo describes an attempt made by a guard.
+type AttemptInfo struct {
+	// Number is the attempt number, starting at 1.
+	Number int
+
+	// PrevErr is the error returned by the previous attempt,
+	// or nil for the first attempt.
+	PrevErr error
+
+	// Name is the name of the guard's operation.
+	Name string
+}
+
+type attemptKey struct{}
+
+// AttemptFromContext returns information about the current attempt
+// from a context passed to a function by Guard.RunContext.
+func AttemptFromContext(ctx context.Context) (AttemptInfo, bool) {
+	info, ok := ctx.Value(attemptKey{}).(AttemptInfo)
+	return info, ok
+}
+
+// RunContext calls function f and keeps retrying while it returns
+// a retryable error, in the same way as Run. Each attempt is passed
+// a context derived from ctx, which has a deadline if AttemptTimeout
+// is set, and from which AttemptFromContext returns the attempt
+// number, the previous attempt's error and the guard's Name.
+//
+// If an attempt fails after its own deadline has passed, but ctx is
+// not done, the error is marked with Retry so the attempt is retried.
+func (g *Guard) RunContext(ctx context.Context, f func(ctx context.Context) error) error {
+	var info AttemptInfo
+	info.Name = g.Name
+	return g.do(ctx, func() error {
+		info.Number++
+		attemptCtx := context.WithValue(ctx, attemptKey{}, info)
+		if g.AttemptTimeout != nil {
+			if timeout := g.AttemptTimeout.Delay(info.Number); timeout > 0 {
+				var cancel context.CancelFunc
+				attemptCtx, cancel = context.WithTimeout(attemptCtx, timeout)
+				defer cancel()
+			}
+		}
+		err := f(attemptCtx)
+		if err != nil && ctx.Err() == nil && errors.Is(attemptCtx.Err(), context.DeadlineExceeded) {
+			err = Retry(err)
+		}
+		info.PrevErr = err
+		return err
+	})
+}
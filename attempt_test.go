@@ -0,0 +1,107 @@
+package errguard
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/jjeffery/errguard/errguardtest"
+)
+
+func TestRunContext(t *testing.T) {
+	errOne := errors.New("one")
+	errTwo := errors.New("two")
+	guard := Guard{
+		Clock: errguardtest.NewAutoClock(time.Now()),
+		Name:  "save order",
+	}
+
+	var infos []AttemptInfo
+	err := guard.RunContext(context.Background(), func(ctx context.Context) error {
+		info, ok := AttemptFromContext(ctx)
+		if !ok {
+			t.Fatal("no attempt info in context")
+		}
+		infos = append(infos, info)
+		switch info.Number {
+		case 1:
+			return Retry(errOne)
+		case 2:
+			return Retry(errTwo)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("got=%v, want nil", err)
+	}
+	if got, want := len(infos), 3; got != want {
+		t.Fatalf("got=%v, want=%v", got, want)
+	}
+	wantPrev := []error{nil, errOne, errTwo}
+	for i, info := range infos {
+		if got, want := info.Number, i+1; got != want {
+			t.Errorf("%d: number: got=%v, want=%v", i, got, want)
+		}
+		if got, want := info.Name, "save order"; got != want {
+			t.Errorf("%d: name: got=%v, want=%v", i, got, want)
+		}
+		if want := wantPrev[i]; !errors.Is(info.PrevErr, want) {
+			t.Errorf("%d: prev: got=%v, want=%v", i, info.PrevErr, want)
+		}
+	}
+
+	if _, ok := AttemptFromContext(context.Background()); ok {
+		t.Errorf("want no attempt info")
+	}
+}
+
+func TestRunContextAttemptTimeout(t *testing.T) {
+	guard := Guard{
+		Backoff:        ConstantBackoff{Interval: time.Millisecond},
+		AttemptTimeout: ExponentialBackoff{Initial: 10 * time.Millisecond, Multiplier: 4},
+		MaxAttempts:    3,
+	}
+
+	var timeouts []time.Duration
+	err := guard.RunContext(context.Background(), func(ctx context.Context) error {
+		deadline, ok := ctx.Deadline()
+		if !ok {
+			t.Fatal("want attempt deadline")
+		}
+		timeouts = append(timeouts, time.Until(deadline))
+		info, _ := AttemptFromContext(ctx)
+		if info.Number < 3 {
+			// slow attempt that exceeds its timeout
+			<-ctx.Done()
+			return ctx.Err()
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("got=%v, want nil", err)
+	}
+	if got, want := len(timeouts), 3; got != want {
+		t.Fatalf("got=%v, want=%v", got, want)
+	}
+	for i, max := range []time.Duration{10 * time.Millisecond, 40 * time.Millisecond, 160 * time.Millisecond} {
+		if got := timeouts[i]; got > max || got < max/2 {
+			t.Errorf("%d: timeout: got=%v, want about %v", i, got, max)
+		}
+	}
+
+	// attempt timeouts are not retried when the parent context is done
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	var attempts int
+	err = guard.RunContext(ctx, func(ctx context.Context) error {
+		attempts++
+		return ctx.Err()
+	})
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("got=%v, want=%v", err, context.Canceled)
+	}
+	if got, want := attempts, 1; got != want {
+		t.Errorf("attempts: got=%v, want=%v", got, want)
+	}
+}
@@ -0,0 +1,129 @@
+package errguard
+
+import (
+	"math"
+	"time"
+)
+
+// Backoff determines how long a guard waits before retrying.
+type Backoff interface {
+	// Delay returns the duration to wait after the given attempt
+	// has failed with a retryable error. The first attempt is 1.
+	Delay(attempt int) time.Duration
+}
+
+// ConstantBackoff waits for the same interval before every retry.
+type ConstantBackoff struct {
+	// Interval is the duration to wait between attempts.
+	Interval time.Duration
+}
+
+// Delay implements the Backoff interface.
+func (b ConstantBackoff) Delay(attempt int) time.Duration {
+	return b.Interval
+}
+
+// ExponentialBackoff multiplies the wait duration after every attempt.
+type ExponentialBackoff struct {
+	// Initial is the duration to wait after the first attempt.
+	// If zero, 100ms is used.
+	Initial time.Duration
+
+	// Multiplier is applied to the wait duration after each attempt.
+	// If less than or equal to 1, a multiplier of 2 is used.
+	Multiplier float64
+
+	// Max is the longest duration to wait. If zero there is no limit.
+	Max time.Duration
+}
+
+// Delay implements the Backoff interface.
+func (b ExponentialBackoff) Delay(attempt int) time.Duration {
+	initial := b.Initial
+	if initial <= 0 {
+		initial = defaultInitialDelay
+	}
+	multiplier := b.Multiplier
+	if multiplier <= 1 {
+		multiplier = 2
+	}
+	if attempt < 1 {
+		attempt = 1
+	}
+	d := float64(initial) * math.Pow(multiplier, float64(attempt-1))
+	return capDelay(d, b.Max)
+}
+
+// FibonacciBackoff increases the wait duration according to the
+// Fibonacci sequence: Initial, Initial, 2*Initial, 3*Initial, 5*Initial, ...
+type FibonacciBackoff struct {
+	// Initial is the duration to wait after the first attempt.
+	// If zero, 100ms is used.
+	Initial time.Duration
+
+	// Max is the longest duration to wait. If zero there is no limit.
+	Max time.Duration
+}
+
+// Delay implements the Backoff interface.
+func (b FibonacciBackoff) Delay(attempt int) time.Duration {
+	initial := b.Initial
+	if initial <= 0 {
+		initial = defaultInitialDelay
+	}
+	prev, curr := 0.0, 1.0
+	for i := 1; i < attempt; i++ {
+		prev, curr = curr, prev+curr
+		if curr*float64(initial) > math.MaxInt64 {
+			break
+		}
+	}
+	return capDelay(curr*float64(initial), b.Max)
+}
+
+// LinearBackoff increases the wait duration by a fixed increment
+// after every attempt.
+type LinearBackoff struct {
+	// Initial is the duration to wait after the first attempt.
+	Initial time.Duration
+
+	// Increment is added to the wait duration after each attempt.
+	// If zero, Initial is used.
+	Increment time.Duration
+
+	// Max is the longest duration to wait. If zero there is no limit.
+	Max time.Duration
+}
+
+// Delay implements the Backoff interface.
+func (b LinearBackoff) Delay(attempt int) time.Duration {
+	increment := b.Increment
+	if increment == 0 {
+		increment = b.Initial
+	}
+	if attempt < 1 {
+		attempt = 1
+	}
+	d := float64(b.Initial) + float64(increment)*float64(attempt-1)
+	return capDelay(d, b.Max)
+}
+
+const defaultInitialDelay = time.Millisecond * 100
+
+// capDelay converts d to a duration no longer than max (if non-zero),
+// guarding against overflow and negative values.
+func capDelay(d float64, max time.Duration) time.Duration {
+	if d < 0 {
+		d = 0
+	}
+	var delay time.Duration
+	if d >= math.MaxInt64 {
+		delay = math.MaxInt64
+	} else {
+		delay = time.Duration(d)
+	}
+	if max > 0 && delay > max {
+		delay = max
+	}
+	return delay
+}
@@ -0,0 +1,105 @@
+package errguard
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/jjeffery/errors"
+)
+
+func TestBackoff(t *testing.T) {
+	ms := time.Millisecond
+	tests := []struct {
+		name    string
+		backoff Backoff
+		want    []time.Duration
+	}{
+		{
+			name:    "constant",
+			backoff: ConstantBackoff{Interval: 5 * ms},
+			want:    []time.Duration{5 * ms, 5 * ms, 5 * ms, 5 * ms},
+		},
+		{
+			name:    "exponential default",
+			backoff: ExponentialBackoff{},
+			want:    []time.Duration{100 * ms, 200 * ms, 400 * ms, 800 * ms, 1600 * ms},
+		},
+		{
+			name:    "exponential capped",
+			backoff: ExponentialBackoff{Initial: 10 * ms, Multiplier: 3, Max: 100 * ms},
+			want:    []time.Duration{10 * ms, 30 * ms, 90 * ms, 100 * ms, 100 * ms},
+		},
+		{
+			name:    "fibonacci",
+			backoff: FibonacciBackoff{Initial: 10 * ms},
+			want:    []time.Duration{10 * ms, 10 * ms, 20 * ms, 30 * ms, 50 * ms, 80 * ms},
+		},
+		{
+			name:    "fibonacci capped",
+			backoff: FibonacciBackoff{Initial: 10 * ms, Max: 25 * ms},
+			want:    []time.Duration{10 * ms, 10 * ms, 20 * ms, 25 * ms, 25 * ms},
+		},
+		{
+			name:    "linear",
+			backoff: LinearBackoff{Initial: 10 * ms, Increment: 5 * ms},
+			want:    []time.Duration{10 * ms, 15 * ms, 20 * ms, 25 * ms},
+		},
+		{
+			name:    "linear default increment",
+			backoff: LinearBackoff{Initial: 10 * ms, Max: 25 * ms},
+			want:    []time.Duration{10 * ms, 20 * ms, 25 * ms, 25 * ms},
+		},
+	}
+
+	for _, tt := range tests {
+		for i, want := range tt.want {
+			if got := tt.backoff.Delay(i + 1); got != want {
+				t.Errorf("%s: attempt %d: got=%v, want=%v", tt.name, i+1, got, want)
+			}
+		}
+	}
+}
+
+func TestBackoffOverflow(t *testing.T) {
+	for _, b := range []Backoff{ExponentialBackoff{}, FibonacciBackoff{}, LinearBackoff{Initial: time.Hour}} {
+		if got := b.Delay(1 << 20); got <= 0 {
+			t.Errorf("%T: got=%v, want positive", b, got)
+		}
+	}
+}
+
+func TestGuardBackoff(t *testing.T) {
+	var delays []int
+	guard := Guard{
+		Backoff: backoffFunc(func(attempt int) time.Duration {
+			delays = append(delays, attempt)
+			return time.Millisecond
+		}),
+	}
+	var attempt int
+	err := guard.Run(context.Background(), func() error {
+		attempt++
+		if attempt < 4 {
+			return Retry(errors.New("test error"))
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("got=%v, want nil", err)
+	}
+	if got, want := len(delays), 3; got != want {
+		t.Fatalf("got=%v, want=%v", got, want)
+	}
+	for i, got := range delays {
+		if want := i + 1; got != want {
+			t.Errorf("got=%v, want=%v", got, want)
+		}
+	}
+}
+
+type backoffFunc func(attempt int) time.Duration
+
+func (f backoffFunc) Delay(attempt int) time.Duration {
+	return f(attempt)
+}
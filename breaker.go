@@ -0,0 +1,226 @@
+package errguard
+
+import (
+	"sync"
+	"time"
+
+	"github.com/jjeffery/kv"
+)
+
+// BreakerState is the state of a circuit breaker.
+type BreakerState int
+
+// Circuit breaker states.
+const (
+	// BreakerClosed allows all attempts.
+	BreakerClosed BreakerState = iota
+
+	// BreakerOpen fails all attempts without calling the function.
+	BreakerOpen
+
+	// BreakerHalfOpen allows a single trial attempt. If it succeeds
+	// the breaker closes, otherwise it opens again.
+	BreakerHalfOpen
+)
+
+func (s BreakerState) String() string {
+	switch s {
+	case BreakerClosed:
+		return "closed"
+	case BreakerOpen:
+		return "open"
+	case BreakerHalfOpen:
+		return "half-open"
+	}
+	return "unknown"
+}
+
+// Breaker is a circuit breaker that can be shared by guards.
+// When attempts keep failing with retryable errors, the breaker
+// opens and guards fail immediately, without calling the function,
+// until the cooldown period has passed.
+//
+// Attempts that succeed, or that fail with an error that is not
+// retryable, count as successes. Attempts that fail with a
+// retryable error count as failures.
+//
+// A Breaker must not be copied after first use.
+type Breaker struct {
+	// ConsecutiveFailures is the number of consecutive failures
+	// that trip the breaker. If zero, and FailureRatio is zero,
+	// 5 is used.
+	ConsecutiveFailures int
+
+	// FailureRatio is the ratio of failures to attempts that trips
+	// the breaker, once at least MinRequests attempts have been
+	// counted. If zero the ratio is not checked.
+	FailureRatio float64
+
+	// MinRequests is the minimum number of attempts counted before
+	// FailureRatio is checked. If zero, 10 is used.
+	MinRequests int
+
+	// Interval is the period after which the counts are reset
+	// while the breaker is closed. If zero, the counts are only
+	// reset when the breaker changes state.
+	Interval time.Duration
+
+	// Cooldown is how long the breaker stays open before allowing
+	// a trial attempt. If the result of a trial attempt has not been
+	// recorded after the same period, another trial is allowed.
+	// If zero, 10 seconds is used.
+	Cooldown time.Duration
+
+	// Clock provides the time for the cooldown and interval.
+	// If nil, the clock of the guard using the breaker is used.
+	Clock Clock
+
+	mutex       sync.Mutex
+	state       BreakerState
+	changed     time.Time // time of last state change or count reset
+	requests    int
+	failures    int
+	consecutive int
+	probing     bool
+	probeStart  time.Time
+
+	// generation changes whenever the state changes and whenever
+	// a trial attempt starts, so that results of attempts allowed
+	// earlier are ignored
+	generation uint64
+}
+
+// State returns the current state of the breaker.
+func (b *Breaker) State() BreakerState {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	return b.state
+}
+
+// allow reports whether an attempt is allowed. If it is, the
+// generation returned must be passed to record with the outcome.
+func (b *Breaker) allow(logger Logger, clock Clock) (uint64, bool) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	now := b.now(clock)
+	switch b.state {
+	case BreakerClosed:
+		if b.Interval > 0 && now.Sub(b.changed) >= b.Interval {
+			b.resetCounts(now)
+		}
+		return b.generation, true
+	case BreakerOpen:
+		if now.Sub(b.changed) < b.cooldown() {
+			return 0, false
+		}
+		b.setState(logger, BreakerHalfOpen, now)
+	}
+	// half-open: allow a single trial attempt at a time, unless
+	// the trial has been running for longer than the cooldown,
+	// in which case its result is assumed to be lost
+	if b.probing && now.Sub(b.probeStart) < b.cooldown() {
+		return 0, false
+	}
+	b.probing = true
+	b.probeStart = now
+	b.generation++
+	return b.generation, true
+}
+
+// record records the outcome of an attempt allowed in generation.
+// Outcomes of attempts allowed before the state last changed, or
+// before the current trial attempt started, are ignored.
+func (b *Breaker) record(logger Logger, clock Clock, generation uint64, failed bool) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	if generation != b.generation {
+		return
+	}
+	now := b.now(clock)
+	switch b.state {
+	case BreakerClosed:
+		b.requests++
+		if !failed {
+			b.consecutive = 0
+			return
+		}
+		b.failures++
+		b.consecutive++
+		if b.shouldTrip() {
+			b.setState(logger, BreakerOpen, now)
+		}
+	case BreakerHalfOpen:
+		b.probing = false
+		if failed {
+			b.setState(logger, BreakerOpen, now)
+		} else {
+			b.setState(logger, BreakerClosed, now)
+		}
+	}
+}
+
+func (b *Breaker) shouldTrip() bool {
+	consecutive := b.ConsecutiveFailures
+	if consecutive <= 0 && b.FailureRatio <= 0 {
+		consecutive = 5
+	}
+	if consecutive > 0 && b.consecutive >= consecutive {
+		return true
+	}
+	if b.FailureRatio > 0 {
+		minRequests := b.MinRequests
+		if minRequests <= 0 {
+			minRequests = 10
+		}
+		if b.requests >= minRequests && float64(b.failures)/float64(b.requests) >= b.FailureRatio {
+			return true
+		}
+	}
+	return false
+}
+
+func (b *Breaker) setState(logger Logger, state BreakerState, now time.Time) {
+	if b.state == state {
+		return
+	}
+	prev := b.state
+	b.state = state
+	b.probing = false
+	b.generation++
+	b.resetCounts(now)
+
+	level := "info"
+	if state == BreakerOpen {
+		level = "warn"
+	}
+	keyvals := []interface{}{
+		kv.P("level", level),
+		kv.P("msg", "circuit breaker state changed"),
+		kv.P("from", prev.String()),
+		kv.P("to", state.String()),
+	}
+	logger.Log(kv.Flatten(keyvals)...)
+}
+
+func (b *Breaker) resetCounts(now time.Time) {
+	b.changed = now
+	b.requests = 0
+	b.failures = 0
+	b.consecutive = 0
+}
+
+func (b *Breaker) cooldown() time.Duration {
+	if b.Cooldown <= 0 {
+		return 10 * time.Second
+	}
+	return b.Cooldown
+}
+
+// now returns the time from the breaker's clock if set,
+// otherwise from clock, the clock of the calling guard.
+func (b *Breaker) now(clock Clock) time.Time {
+	if b.Clock != nil {
+		return b.Clock.Now()
+	}
+	return clock.Now()
+}
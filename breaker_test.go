@@ -0,0 +1,283 @@
+package errguard
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/jjeffery/errguard/errguardtest"
+	"github.com/jjeffery/kv"
+)
+
+func TestBreaker(t *testing.T) {
+	clock := errguardtest.NewAutoClock(time.Date(2017, 1, 2, 3, 4, 5, 0, time.UTC))
+	var logs []kv.List
+	breaker := &Breaker{
+		ConsecutiveFailures: 3,
+		Cooldown:            time.Minute,
+	}
+	guard := Guard{
+		Clock:       clock,
+		Backoff:     ConstantBackoff{Interval: time.Millisecond},
+		MaxAttempts: 2,
+		Breaker:     breaker,
+		Logger: loggerFunc(func(v ...interface{}) error {
+			if getString(kv.List(v), "msg") == "circuit breaker state changed" {
+				logs = append(logs, kv.List(v))
+			}
+			return nil
+		}),
+	}
+	testErr := errors.New("test error")
+	var calls int
+	fail := func() error {
+		calls++
+		return Retry(testErr)
+	}
+	succeed := func() error {
+		calls++
+		return nil
+	}
+
+	// two attempts, both fail
+	guard.Run(context.Background(), fail)
+	if got, want := breaker.State(), BreakerClosed; got != want {
+		t.Fatalf("state: got=%v, want=%v", got, want)
+	}
+
+	// first attempt fails and trips the breaker, second is not attempted
+	calls = 0
+	err := guard.Run(context.Background(), fail)
+	if got, want := calls, 1; got != want {
+		t.Errorf("calls: got=%v, want=%v", got, want)
+	}
+	if got, want := breaker.State(), BreakerOpen; got != want {
+		t.Fatalf("state: got=%v, want=%v", got, want)
+	}
+	if !errors.Is(err, ErrBreakerOpen) || !errors.Is(err, testErr) {
+		t.Errorf("got=%v, want %v and %v", err, ErrBreakerOpen, testErr)
+	}
+
+	// open breaker fails immediately
+	calls = 0
+	err = guard.Run(context.Background(), succeed)
+	if got, want := calls, 0; got != want {
+		t.Errorf("calls: got=%v, want=%v", got, want)
+	}
+	if got, want := err.Error(), ErrBreakerOpen.Error(); got != want {
+		t.Errorf("got=%q, want=%q", got, want)
+	}
+
+	// after the cooldown, a failed trial opens the breaker again
+	clock.Advance(time.Minute)
+	calls = 0
+	guard.Run(context.Background(), fail)
+	if got, want := calls, 1; got != want {
+		t.Errorf("calls: got=%v, want=%v", got, want)
+	}
+	if got, want := breaker.State(), BreakerOpen; got != want {
+		t.Fatalf("state: got=%v, want=%v", got, want)
+	}
+
+	// after the cooldown, a successful trial closes the breaker
+	clock.Advance(time.Minute)
+	if err := guard.Run(context.Background(), succeed); err != nil {
+		t.Fatalf("got=%v, want nil", err)
+	}
+	if got, want := breaker.State(), BreakerClosed; got != want {
+		t.Fatalf("state: got=%v, want=%v", got, want)
+	}
+
+	wantTransitions := [][2]string{
+		{"closed", "open"},
+		{"open", "half-open"},
+		{"half-open", "open"},
+		{"open", "half-open"},
+		{"half-open", "closed"},
+	}
+	if got, want := len(logs), len(wantTransitions); got != want {
+		t.Fatalf("logs: got=%v, want=%v", got, want)
+	}
+	for i, want := range wantTransitions {
+		if got := [2]string{getString(logs[i], "from"), getString(logs[i], "to")}; got != want {
+			t.Errorf("%d: got=%v, want=%v", i, got, want)
+		}
+	}
+}
+
+func TestBreakerFailureRatio(t *testing.T) {
+	clock := errguardtest.NewAutoClock(time.Date(2017, 1, 2, 3, 4, 5, 0, time.UTC))
+	breaker := &Breaker{
+		FailureRatio: 0.5,
+		MinRequests:  4,
+		Interval:     time.Minute,
+	}
+	var logger noopLogger
+
+	record := func(outcomes ...bool) {
+		for _, failed := range outcomes {
+			generation, ok := breaker.allow(logger, clock)
+			if !ok {
+				t.Fatalf("want allowed")
+			}
+			breaker.record(logger, clock, generation, failed)
+		}
+	}
+
+	// counts are reset after the interval
+	record(true, false, true)
+	clock.Advance(time.Minute)
+	record(false, true, false)
+	if got, want := breaker.State(), BreakerClosed; got != want {
+		t.Fatalf("state: got=%v, want=%v", got, want)
+	}
+
+	record(true)
+	if got, want := breaker.State(), BreakerOpen; got != want {
+		t.Fatalf("state: got=%v, want=%v", got, want)
+	}
+
+	// only one trial attempt at a time when half-open
+	clock.Advance(10 * time.Second)
+	if _, ok := breaker.allow(logger, clock); !ok {
+		t.Fatalf("want trial allowed")
+	}
+	if _, ok := breaker.allow(logger, clock); ok {
+		t.Fatalf("want second trial denied")
+	}
+}
+
+func TestBreakerLateResult(t *testing.T) {
+	clock := errguardtest.NewAutoClock(time.Date(2017, 1, 2, 3, 4, 5, 0, time.UTC))
+	breaker := &Breaker{
+		ConsecutiveFailures: 1,
+		Cooldown:            time.Minute,
+		// the breaker's clock is used instead of the guard's clock
+		Clock: clock,
+	}
+	var logger noopLogger
+	allow := func() uint64 {
+		generation, ok := breaker.allow(logger, systemClock{})
+		if !ok {
+			t.Fatal("want allowed")
+		}
+		return generation
+	}
+
+	// attempt a starts while closed, then b trips the breaker
+	a := allow()
+	breaker.record(logger, systemClock{}, allow(), true)
+	if got, want := breaker.State(), BreakerOpen; got != want {
+		t.Fatalf("state: got=%v, want=%v", got, want)
+	}
+
+	// c is the trial attempt, and the late result of a is ignored
+	clock.Advance(time.Minute)
+	c := allow()
+	breaker.record(logger, systemClock{}, a, false)
+	if got, want := breaker.State(), BreakerHalfOpen; got != want {
+		t.Fatalf("state: got=%v, want=%v", got, want)
+	}
+
+	// the trial's result is lost, so d becomes the trial attempt,
+	// and the late result of c is ignored
+	clock.Advance(time.Minute)
+	d := allow()
+	breaker.record(logger, systemClock{}, c, false)
+	if got, want := breaker.State(), BreakerHalfOpen; got != want {
+		t.Fatalf("state: got=%v, want=%v", got, want)
+	}
+	breaker.record(logger, systemClock{}, d, true)
+	if got, want := breaker.State(), BreakerOpen; got != want {
+		t.Fatalf("state: got=%v, want=%v", got, want)
+	}
+}
+
+func TestBreakerPanickingTrial(t *testing.T) {
+	clock := errguardtest.NewAutoClock(time.Date(2017, 1, 2, 3, 4, 5, 0, time.UTC))
+	breaker := &Breaker{
+		ConsecutiveFailures: 1,
+		Cooldown:            time.Minute,
+	}
+	guard := Guard{
+		Clock:       clock,
+		MaxAttempts: 1,
+		Breaker:     breaker,
+	}
+	guard.Run(context.Background(), func() error {
+		return Retry(errors.New("test error"))
+	})
+
+	// the trial attempt panics, which counts as a failure
+	clock.Advance(time.Minute)
+	func() {
+		defer func() {
+			if r := recover(); r == nil {
+				t.Fatal("want panic")
+			}
+		}()
+		guard.Run(context.Background(), func() error {
+			panic("test panic")
+		})
+	}()
+	if got, want := breaker.State(), BreakerOpen; got != want {
+		t.Fatalf("state: got=%v, want=%v", got, want)
+	}
+
+	// after the cooldown, another trial is allowed
+	clock.Advance(time.Minute)
+	var calls int
+	err := guard.Run(context.Background(), func() error {
+		calls++
+		return nil
+	})
+	if err != nil || calls != 1 {
+		t.Errorf("got=%v, calls=%v, want nil, 1", err, calls)
+	}
+	if got, want := breaker.State(), BreakerClosed; got != want {
+		t.Errorf("state: got=%v, want=%v", got, want)
+	}
+}
+
+func TestBreakerAbandonedTrial(t *testing.T) {
+	clock := errguardtest.NewAutoClock(time.Date(2017, 1, 2, 3, 4, 5, 0, time.UTC))
+	breaker := &Breaker{
+		ConsecutiveFailures: 1,
+		Cooldown:            time.Minute,
+	}
+	guard := Guard{
+		Clock:       clock,
+		MaxAttempts: 1,
+		Breaker:     breaker,
+	}
+	guard.Run(context.Background(), func() error {
+		return Retry(errors.New("test error"))
+	})
+
+	// the trial attempt never reports its result
+	clock.Advance(time.Minute)
+	if r := guard.Start(context.Background()); !r.Next() {
+		t.Fatal("want trial attempt")
+	}
+
+	// while the trial is running, other attempts are not allowed
+	var calls int
+	succeed := func() error {
+		calls++
+		return nil
+	}
+	err := guard.Run(context.Background(), succeed)
+	if !errors.Is(err, ErrBreakerOpen) || calls != 0 {
+		t.Errorf("got=%v, calls=%v, want %v, 0", err, calls, ErrBreakerOpen)
+	}
+
+	// after the cooldown, the trial is assumed lost and another is allowed
+	clock.Advance(time.Minute)
+	if err := guard.Run(context.Background(), succeed); err != nil || calls != 1 {
+		t.Errorf("got=%v, calls=%v, want nil, 1", err, calls)
+	}
+	if got, want := breaker.State(), BreakerClosed; got != want {
+		t.Errorf("state: got=%v, want=%v", got, want)
+	}
+}
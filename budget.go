@@ -0,0 +1,97 @@
+package errguard
+
+import (
+	"sync"
+)
+
+// Budget limits the number of retries made by all of the guards
+// that share it, so that an outage does not multiply the load on
+// a struggling service.
+//
+// Budget is a token bucket. Every operation run by a guard adds
+// Ratio tokens to the bucket, up to Max tokens, and every retry
+// takes one token. When there is less than one token in the bucket,
+// guards give up instead of retrying, and return an error that
+// wraps ErrBudgetExhausted. The bucket starts full.
+//
+// A Budget must not be copied after first use.
+type Budget struct {
+	// Ratio is the number of retries allowed per operation.
+	// If zero, 0.1 is used, which allows one retry for every
+	// ten operations.
+	Ratio float64
+
+	// Max is the maximum number of tokens in the bucket, which
+	// is the number of retries allowed in a burst. If zero,
+	// 10 is used.
+	Max float64
+
+	mutex       sync.Mutex
+	initialized bool
+	stats       BudgetStats
+}
+
+// BudgetStats contains statistics about a budget's usage,
+// suitable for reporting as metrics.
+type BudgetStats struct {
+	Tokens    float64 // Tokens currently available
+	Requests  int64   // Operations that have deposited tokens
+	Retries   int64   // Retries allowed by the budget
+	Exhausted int64   // Retries denied because the budget was exhausted
+}
+
+// Stats returns the budget's usage statistics.
+func (b *Budget) Stats() BudgetStats {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	b.init()
+	return b.stats
+}
+
+// deposit records an operation, adding tokens to the bucket.
+func (b *Budget) deposit() {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	b.init()
+	b.stats.Requests++
+	b.stats.Tokens += b.ratio()
+	if max := b.max(); b.stats.Tokens > max {
+		b.stats.Tokens = max
+	}
+}
+
+// withdraw takes a token for a retry, and reports whether
+// there was a token available.
+func (b *Budget) withdraw() bool {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	b.init()
+	if b.stats.Tokens < 1 {
+		b.stats.Exhausted++
+		return false
+	}
+	b.stats.Tokens--
+	b.stats.Retries++
+	return true
+}
+
+func (b *Budget) init() {
+	if !b.initialized {
+		b.stats.Tokens = b.max()
+		b.initialized = true
+	}
+}
+
+func (b *Budget) ratio() float64 {
+	if b.Ratio <= 0 {
+		return 0.1
+	}
+	return b.Ratio
+}
+
+func (b *Budget) max() float64 {
+	if b.Max <= 0 {
+		return 10
+	}
+	return b.Max
+}
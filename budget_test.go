@@ -0,0 +1,71 @@
+package errguard
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestBudget(t *testing.T) {
+	budget := &Budget{Ratio: 0.5, Max: 2}
+	if got, want := budget.Stats(), (BudgetStats{Tokens: 2}); got != want {
+		t.Errorf("got=%+v, want=%+v", got, want)
+	}
+
+	guard := Guard{
+		Backoff: ConstantBackoff{Interval: time.Millisecond},
+		Budget:  budget,
+	}
+	testErr := errors.New("test error")
+	var attempts int
+	err := guard.Run(context.Background(), func() error {
+		attempts++
+		return Retry(testErr)
+	})
+	if !errors.Is(err, ErrBudgetExhausted) {
+		t.Errorf("got=%v, want=%v", err, ErrBudgetExhausted)
+	}
+	if !errors.Is(err, testErr) {
+		t.Errorf("got=%v, want=%v", err, testErr)
+	}
+	// the bucket starts with 2 tokens, and the first attempt
+	// adds 0.5 but the bucket cannot hold more than 2
+	if got, want := attempts, 3; got != want {
+		t.Errorf("attempts: got=%v, want=%v", got, want)
+	}
+	if got, want := budget.Stats(), (BudgetStats{Tokens: 0, Requests: 1, Retries: 2, Exhausted: 1}); got != want {
+		t.Errorf("got=%+v, want=%+v", got, want)
+	}
+
+	// successful operations refill the budget
+	for i := 0; i < 2; i++ {
+		if err := guard.Run(context.Background(), func() error { return nil }); err != nil {
+			t.Fatal(err)
+		}
+	}
+	attempts = 0
+	err = guard.Run(context.Background(), func() error {
+		attempts++
+		if attempts < 2 {
+			return Retry(testErr)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Errorf("got=%v, want nil", err)
+	}
+	if got, want := budget.Stats(), (BudgetStats{Tokens: 0.5, Requests: 4, Retries: 3, Exhausted: 1}); got != want {
+		t.Errorf("got=%+v, want=%+v", got, want)
+	}
+}
+
+func TestBudgetDefaults(t *testing.T) {
+	var budget Budget
+	for i := 0; i < 20; i++ {
+		budget.deposit()
+	}
+	if got, want := budget.Stats().Tokens, 10.0; got != want {
+		t.Errorf("got=%v, want=%v", got, want)
+	}
+}
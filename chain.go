@@ -0,0 +1,52 @@
+package errguard
+
+// WalkChain calls fn for err and every error in its chain, stopping
+// when fn returns true. The chain is walked depth-first, following
+// Unwrap() error, Unwrap() []error (as returned by errors.Join) and
+// the Cause() error method used by github.com/pkg/errors and similar
+// packages. It reports whether fn returned true.
+//
+// This is the chain searched by the default ShouldRetry and by
+// IsPermanent, and is useful for writing classifiers that
+// recognise errors the same way.
+func WalkChain(err error, fn func(error) bool) bool {
+	for err != nil {
+		if fn(err) {
+			return true
+		}
+		switch e := err.(type) {
+		case interface{ Unwrap() error }:
+			err = e.Unwrap()
+		case interface{ Unwrap() []error }:
+			for _, err := range e.Unwrap() {
+				if WalkChain(err, fn) {
+					return true
+				}
+			}
+			return false
+		case interface{ Cause() error }:
+			cause := e.Cause()
+			if cause == err {
+				return false
+			}
+			err = cause
+		default:
+			return false
+		}
+	}
+	return false
+}
+
+// findInChain returns the first error in err's chain that
+// implements T.
+func findInChain[T any](err error) (T, bool) {
+	var target T
+	found := WalkChain(err, func(err error) bool {
+		if t, ok := err.(T); ok {
+			target = t
+			return true
+		}
+		return false
+	})
+	return target, found
+}
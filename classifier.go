@@ -0,0 +1,97 @@
+package errguard
+
+import (
+	"time"
+)
+
+// Classifier decides what a guard should do after an
+// attempt fails with an error.
+type Classifier interface {
+	Classify(err error) Decision
+}
+
+// ClassifierFunc is an adapter to allow the use of an ordinary
+// function as a Classifier.
+type ClassifierFunc func(err error) Decision
+
+// Classify implements the Classifier interface.
+func (f ClassifierFunc) Classify(err error) Decision {
+	return f(err)
+}
+
+// ShouldRetryFunc is an adapter to allow the use of a function
+// that returns true if an error should be retried as a Classifier.
+type ShouldRetryFunc func(err error) bool
+
+// Classify implements the Classifier interface.
+func (f ShouldRetryFunc) Classify(err error) Decision {
+	if f(err) {
+		return DecideRetry()
+	}
+	return DecideStop()
+}
+
+// Decision describes what a guard should do after an attempt
+// fails with an error. The zero value stops retrying and returns
+// the error.
+type Decision struct {
+	// Retry is true if the guard should retry the operation.
+	Retry bool
+
+	// Delay is the time to wait before retrying. If zero, the
+	// delay is computed by the guard's backoff.
+	Delay time.Duration
+
+	// MaxAttempts, if non-zero, limits the number of attempts
+	// that fail with an error of the decision's Class, in addition
+	// to any limit set for the guard.
+	MaxAttempts int
+
+	// Class identifies the class of error for MaxAttempts. Failed
+	// attempts are counted separately for each class, so errors of
+	// other classes do not count towards the limit. Decisions
+	// without a class are counted together.
+	Class string
+
+	// Err is returned instead of the attempt's error when
+	// the guard stops retrying.
+	Err error
+}
+
+// DecideRetry returns a decision to retry after the delay
+// computed by the guard's backoff.
+func DecideRetry() Decision {
+	return Decision{Retry: true}
+}
+
+// DecideRetryAfter returns a decision to retry after waiting
+// for duration d.
+func DecideRetryAfter(d time.Duration) Decision {
+	return Decision{Retry: true, Delay: d}
+}
+
+// DecideStop returns a decision to stop retrying and return
+// the attempt's error.
+func DecideStop() Decision {
+	return Decision{}
+}
+
+// DecideStopWith returns a decision to stop retrying and
+// return err instead of the attempt's error.
+func DecideStopWith(err error) Decision {
+	return Decision{Err: err}
+}
+
+// WithMaxAttempts returns a copy of the decision that limits
+// the number of attempts that fail with an error of its class to n.
+func (d Decision) WithMaxAttempts(n int) Decision {
+	d.MaxAttempts = n
+	return d
+}
+
+// WithClass returns a copy of the decision with its class set,
+// so that MaxAttempts only counts errors of that class.
+func (d Decision) WithClass(class string) Decision {
+	d.Class = class
+	return d
+}
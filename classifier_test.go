@@ -0,0 +1,88 @@
+package errguard
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestShouldRetryFunc(t *testing.T) {
+	c := ShouldRetryFunc(ShouldRetry)
+	base := errors.New("test error")
+	if got, want := c.Classify(base), DecideStop(); got != want {
+		t.Errorf("got=%+v, want=%+v", got, want)
+	}
+	if got, want := c.Classify(Retry(base)), DecideRetry(); got != want {
+		t.Errorf("got=%+v, want=%+v", got, want)
+	}
+}
+
+func TestGuardClassifier(t *testing.T) {
+	errRetry := errors.New("retry")
+	errRetryLimited := errors.New("retry limited")
+	errRetryClass := errors.New("retry class")
+	errRetryAfter := errors.New("retry after")
+	errStopWith := errors.New("stop with")
+	errReplaced := errors.New("replaced")
+	errOther := errors.New("other")
+
+	guard := Guard{
+		Backoff:    ConstantBackoff{Interval: time.Hour},
+		MaxElapsed: time.Minute,
+		// should be ignored because Classifier is set
+		ShouldRetry: func(err error) bool { return false },
+		Classifier: ClassifierFunc(func(err error) Decision {
+			switch err {
+			case errRetry:
+				return DecideRetry()
+			case errRetryLimited:
+				return DecideRetryAfter(time.Millisecond).WithMaxAttempts(3)
+			case errRetryClass:
+				return DecideRetryAfter(time.Millisecond).WithMaxAttempts(2).WithClass("class")
+			case errRetryAfter:
+				return DecideRetryAfter(time.Millisecond)
+			case errStopWith:
+				return DecideStopWith(errReplaced)
+			}
+			return DecideStop()
+		}),
+	}
+
+	tests := []struct {
+		errs         []error
+		wantAttempts int
+		wantErr      error
+	}{
+		{errs: []error{errRetryAfter, errRetryAfter, nil}, wantAttempts: 3, wantErr: nil},
+		{errs: []error{errRetryAfter, errStopWith}, wantAttempts: 2, wantErr: errReplaced},
+		{errs: []error{errRetryAfter, Permanent(errRetryAfter)}, wantAttempts: 2, wantErr: errRetryAfter},
+		{errs: []error{errRetryLimited, errRetryLimited, errRetryLimited, nil}, wantAttempts: 3, wantErr: ErrMaxAttempts},
+		{errs: []error{errRetry}, wantAttempts: 1, wantErr: ErrMaxElapsed},
+		// other classes do not count towards the limit
+		{errs: []error{errRetryAfter, errRetryAfter, errRetryAfter, errRetryClass, errRetryAfter, errRetryClass}, wantAttempts: 6, wantErr: ErrMaxAttempts},
+		{errs: []error{errRetryAfter, errRetryAfter, errRetryAfter, errRetryClass, nil}, wantAttempts: 5, wantErr: nil},
+		{errs: []error{errOther}, wantAttempts: 1, wantErr: errOther},
+	}
+
+	for i, tt := range tests {
+		var attempt int
+		err := guard.Run(context.Background(), func() error {
+			err := tt.errs[attempt]
+			attempt++
+			return err
+		})
+		if got, want := attempt, tt.wantAttempts; got != want {
+			t.Errorf("%d: attempts: got=%v, want=%v", i, got, want)
+		}
+		if tt.wantErr == nil {
+			if err != nil {
+				t.Errorf("%d: got=%v, want nil", i, err)
+			}
+			continue
+		}
+		if !errors.Is(err, tt.wantErr) {
+			t.Errorf("%d: got=%v, want=%v", i, err, tt.wantErr)
+		}
+	}
+}
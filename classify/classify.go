@@ -0,0 +1,22 @@
+// Package classify provides functions that recognise errors
+// with a reasonable chance of succeeding if retried.
+//
+// The functions are suitable for use as the ShouldRetry field
+// of an errguard.Guard, and the classifiers as its Classifier field.
+// They recognise errors by the methods and fields that drivers and
+// libraries expose, so this package does not depend on any of those
+// drivers or libraries.
+package classify
+
+// Any returns a function that reports whether any of fns
+// reports that err should be retried.
+func Any(fns ...func(err error) bool) func(err error) bool {
+	return func(err error) bool {
+		for _, fn := range fns {
+			if fn(err) {
+				return true
+			}
+		}
+		return false
+	}
+}
@@ -0,0 +1,18 @@
+package classify
+
+import (
+	"github.com/jjeffery/errguard"
+)
+
+// Network reports whether err indicates a transient network failure.
+// It is errguard.IsTransientNetworkError with idempotent set to false.
+func Network(err error) bool {
+	return errguard.IsTransientNetworkError(err, false)
+}
+
+// NetworkIdempotent is like Network, but also treats io.ErrUnexpectedEOF
+// as retryable. It is errguard.IsTransientNetworkError with idempotent
+// set to true.
+func NetworkIdempotent(err error) bool {
+	return errguard.IsTransientNetworkError(err, true)
+}
@@ -0,0 +1,57 @@
+package classify
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"syscall"
+	"testing"
+)
+
+// timeoutError is a net.Error that reports a timeout.
+type timeoutError struct{}
+
+func (timeoutError) Error() string   { return "i/o timeout" }
+func (timeoutError) Timeout() bool   { return true }
+func (timeoutError) Temporary() bool { return true }
+
+func TestNetwork(t *testing.T) {
+	opErr := func(err error) error {
+		return &net.OpError{Op: "read", Net: "tcp", Err: err}
+	}
+	tests := []struct {
+		err            error
+		want           bool
+		wantIdempotent bool
+	}{
+		{err: nil},
+		{err: errors.New("some error")},
+		{err: opErr(timeoutError{}), want: true, wantIdempotent: true},
+		{err: opErr(os.NewSyscallError("read", syscall.ECONNRESET)), want: true, wantIdempotent: true},
+		{err: opErr(os.NewSyscallError("connect", syscall.ECONNREFUSED)), want: true, wantIdempotent: true},
+		{err: opErr(os.NewSyscallError("write", syscall.EPIPE)), want: true, wantIdempotent: true},
+		{err: opErr(os.NewSyscallError("connect", syscall.EACCES))},
+		{err: &net.DNSError{Err: "server misbehaving", IsTemporary: true}, want: true, wantIdempotent: true},
+		{err: &net.DNSError{Err: "i/o timeout", IsTimeout: true}, want: true, wantIdempotent: true},
+		{err: &net.DNSError{Err: "no such host", IsNotFound: true}},
+		{err: fmt.Errorf("calling service: %w", opErr(syscall.ECONNRESET)), want: true, wantIdempotent: true},
+		{err: io.ErrUnexpectedEOF, wantIdempotent: true},
+		{err: fmt.Errorf("reading response: %w", io.ErrUnexpectedEOF), wantIdempotent: true},
+		{err: io.EOF},
+		{err: context.DeadlineExceeded},
+		{err: opErr(context.DeadlineExceeded)},
+		{err: context.Canceled},
+	}
+
+	for i, tt := range tests {
+		if got := Network(tt.err); got != tt.want {
+			t.Errorf("%d: Network(%v): got=%v, want=%v", i, tt.err, got, tt.want)
+		}
+		if got := NetworkIdempotent(tt.err); got != tt.wantIdempotent {
+			t.Errorf("%d: NetworkIdempotent(%v): got=%v, want=%v", i, tt.err, got, tt.wantIdempotent)
+		}
+	}
+}
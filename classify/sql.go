@@ -0,0 +1,12 @@
+package classify
+
+import (
+	"github.com/jjeffery/errguard"
+)
+
+// SQL reports whether any error in err's chain indicates a
+// deadlock, serialization failure or lock timeout reported by
+// a database. See errguard.IsTransientSQLError.
+func SQL(err error) bool {
+	return errguard.IsTransientSQLError(err)
+}
@@ -0,0 +1,108 @@
+package classify
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/jjeffery/errguard"
+)
+
+// pqError has the shape of *pq.Error from github.com/lib/pq.
+type pqError struct {
+	Code    string
+	Message string
+}
+
+func (e *pqError) Error() string { return "pq: " + e.Message }
+
+// pgError has the shape of *pgconn.PgError from github.com/jackc/pgx.
+type pgError struct {
+	Code string
+}
+
+func (e *pgError) Error() string    { return "pg error " + e.Code }
+func (e *pgError) SQLState() string { return e.Code }
+
+// mysqlError has the shape of *mysql.MySQLError from github.com/go-sql-driver/mysql.
+type mysqlError struct {
+	Number   uint16
+	SQLState [5]byte
+	Message  string
+}
+
+func (e *mysqlError) Error() string { return fmt.Sprintf("Error %d: %s", e.Number, e.Message) }
+
+// mssqlError has the shape of mssql.Error from github.com/microsoft/go-mssqldb.
+type mssqlError struct {
+	Number  int32
+	Message string
+}
+
+func (e mssqlError) Error() string         { return "mssql: " + e.Message }
+func (e mssqlError) SQLErrorNumber() int32 { return e.Number }
+
+// sqliteError has the shape of sqlite3.Error from github.com/mattn/go-sqlite3.
+type sqliteErrNo int
+type sqliteErrNoExtended int
+
+type sqliteError struct {
+	Code         sqliteErrNo
+	ExtendedCode sqliteErrNoExtended
+}
+
+func (e sqliteError) Error() string { return fmt.Sprintf("sqlite error %d", e.Code) }
+
+// httpError has an integer Code field, but is not a database error.
+type httpError struct {
+	Code int
+}
+
+func (e *httpError) Error() string { return fmt.Sprintf("http status %d", e.Code) }
+
+func TestSQL(t *testing.T) {
+	tests := []struct {
+		err  error
+		want bool
+	}{
+		{err: nil, want: false},
+		{err: errors.New("some error"), want: false},
+		{err: &pqError{Code: "40001"}, want: true},
+		{err: &pqError{Code: "40P01"}, want: true},
+		{err: &pqError{Code: "55P03"}, want: true},
+		{err: &pqError{Code: "23505"}, want: false},
+		{err: (*pqError)(nil), want: false},
+		{err: &pgError{Code: "40P01"}, want: true},
+		{err: &pgError{Code: "42P01"}, want: false},
+		{err: &mysqlError{Number: 1213}, want: true},
+		{err: &mysqlError{Number: 1205}, want: true},
+		{err: &mysqlError{Number: 1062}, want: false},
+		{err: mssqlError{Number: 1205}, want: true},
+		{err: mssqlError{Number: 2627}, want: false},
+		{err: sqliteError{Code: 5, ExtendedCode: 5}, want: true},
+		{err: sqliteError{Code: 6, ExtendedCode: 262}, want: true},
+		{err: sqliteError{Code: 19, ExtendedCode: 2067}, want: false},
+		{err: &httpError{Code: 5}, want: false},
+		{err: fmt.Errorf("saving order: %w", &pqError{Code: "40001"}), want: true},
+		{err: errors.Join(errors.New("rollback failed"), &mysqlError{Number: 1213}), want: true},
+	}
+
+	for i, tt := range tests {
+		if got := SQL(tt.err); got != tt.want {
+			t.Errorf("%d: %v: got=%v, want=%v", i, tt.err, got, tt.want)
+		}
+	}
+}
+
+func TestAny(t *testing.T) {
+	shouldRetry := Any(errguard.ShouldRetry, SQL)
+	if !shouldRetry(errguard.Retry(errors.New("retry"))) {
+		t.Errorf("want true for Retry")
+	}
+	if !shouldRetry(&pqError{Code: "40001"}) {
+		t.Errorf("want true for serialization failure")
+	}
+	if shouldRetry(errors.New("other")) {
+		t.Errorf("want false for other error")
+	}
+}
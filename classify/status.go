@@ -0,0 +1,145 @@
+package classify
+
+import (
+	"reflect"
+	"time"
+
+	"github.com/jjeffery/errguard"
+)
+
+// Status codes, as defined by google.golang.org/grpc/codes.
+const (
+	codeDeadlineExceeded  = 4
+	codeResourceExhausted = 8
+	codeAborted           = 10
+	codeUnavailable       = 14
+)
+
+// StatusClassifier is an errguard.Classifier for errors that carry
+// a gRPC-style status code. It finds the code from the first error
+// in the chain with a GRPCStatus() method returning a value with a
+// Code() method, or with a Code() method returning an integer, so
+// it does not depend on the gRPC packages.
+//
+// Errors with codes Unavailable, Aborted and ResourceExhausted are
+// retried. If the status has a detail with a GetRetryDelay() method
+// (such as errdetails.RetryInfo), the guard waits for that delay.
+type StatusClassifier struct {
+	// RetryDeadlineExceeded is true if errors with code
+	// DeadlineExceeded should be retried.
+	RetryDeadlineExceeded bool
+}
+
+// Classify implements the errguard.Classifier interface.
+func (c StatusClassifier) Classify(err error) errguard.Decision {
+	var code uint64
+	var status reflect.Value
+	found := errguard.WalkChain(err, func(err error) bool {
+		var ok bool
+		code, status, ok = statusCode(err)
+		return ok
+	})
+	if !found {
+		return errguard.DecideStop()
+	}
+	switch code {
+	case codeUnavailable, codeAborted, codeResourceExhausted:
+	case codeDeadlineExceeded:
+		if !c.RetryDeadlineExceeded {
+			return errguard.DecideStop()
+		}
+	default:
+		return errguard.DecideStop()
+	}
+	if d, ok := retryDelay(status); ok {
+		return errguard.DecideRetryAfter(d)
+	}
+	return errguard.DecideRetry()
+}
+
+// ShouldRetry reports whether err should be retried. It is suitable
+// for use as the ShouldRetry field of an errguard.Guard, but ignores
+// any retry delay.
+func (c StatusClassifier) ShouldRetry(err error) bool {
+	return c.Classify(err).Retry
+}
+
+// statusCode returns the status code of err, and the status value
+// if err has a GRPCStatus method.
+func statusCode(err error) (uint64, reflect.Value, bool) {
+	v := reflect.ValueOf(err)
+	if v.Kind() == reflect.Ptr && v.IsNil() {
+		return 0, reflect.Value{}, false
+	}
+	if status, ok := callMethod(v, "GRPCStatus"); ok {
+		if status.Kind() == reflect.Ptr && status.IsNil() {
+			// a nil status means OK
+			return 0, reflect.Value{}, false
+		}
+		code, ok := callMethod(status, "Code")
+		if !ok {
+			return 0, reflect.Value{}, false
+		}
+		n, ok := uintValue(code)
+		return n, status, ok
+	}
+	if code, ok := callMethod(v, "Code"); ok {
+		n, ok := uintValue(code)
+		return n, reflect.Value{}, ok
+	}
+	return 0, reflect.Value{}, false
+}
+
+// retryDelay returns the retry delay from the status details.
+func retryDelay(status reflect.Value) (time.Duration, bool) {
+	if !status.IsValid() {
+		return 0, false
+	}
+	details, ok := callMethod(status, "Details")
+	if !ok || details.Kind() != reflect.Slice {
+		return 0, false
+	}
+	for i := 0; i < details.Len(); i++ {
+		detail := details.Index(i)
+		if detail.Kind() == reflect.Interface {
+			detail = detail.Elem()
+		}
+		if !detail.IsValid() {
+			continue
+		}
+		delay, ok := callMethod(detail, "GetRetryDelay")
+		if !ok || (delay.Kind() == reflect.Ptr && delay.IsNil()) {
+			continue
+		}
+		d, ok := callMethod(delay, "AsDuration")
+		if !ok {
+			continue
+		}
+		if d, ok := d.Interface().(time.Duration); ok {
+			return d, true
+		}
+	}
+	return 0, false
+}
+
+// callMethod calls the named method of v if it takes no arguments
+// and returns a single result.
+func callMethod(v reflect.Value, name string) (reflect.Value, bool) {
+	m := v.MethodByName(name)
+	if !m.IsValid() || m.Type().NumIn() != 0 || m.Type().NumOut() != 1 {
+		return reflect.Value{}, false
+	}
+	return m.Call(nil)[0], true
+}
+
+func uintValue(v reflect.Value) (uint64, bool) {
+	switch v.Kind() {
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return v.Uint(), true
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if v.Int() >= 0 {
+			return uint64(v.Int()), true
+		}
+	}
+	return 0, false
+}
@@ -0,0 +1,108 @@
+package classify
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/jjeffery/errguard"
+)
+
+// fakeCode has the shape of codes.Code from google.golang.org/grpc/codes.
+type fakeCode uint32
+
+const (
+	fakeOK                fakeCode = 0
+	fakeDeadlineExceeded  fakeCode = 4
+	fakeNotFound          fakeCode = 5
+	fakeResourceExhausted fakeCode = 8
+	fakeAborted           fakeCode = 10
+	fakeUnavailable       fakeCode = 14
+)
+
+// fakeStatus has the shape of *status.Status from google.golang.org/grpc/status.
+type fakeStatus struct {
+	code    fakeCode
+	details []any
+}
+
+func (s *fakeStatus) Code() fakeCode { return s.code }
+func (s *fakeStatus) Details() []any { return s.details }
+
+// fakeStatusError has the shape of the errors returned by gRPC clients.
+type fakeStatusError struct {
+	status *fakeStatus
+}
+
+func (e *fakeStatusError) Error() string           { return fmt.Sprintf("rpc error: code = %d", e.status.code) }
+func (e *fakeStatusError) GRPCStatus() *fakeStatus { return e.status }
+
+// fakeDuration has the shape of *durationpb.Duration.
+type fakeDuration struct {
+	d time.Duration
+}
+
+func (d *fakeDuration) AsDuration() time.Duration { return d.d }
+
+// fakeRetryInfo has the shape of *errdetails.RetryInfo.
+type fakeRetryInfo struct {
+	delay *fakeDuration
+}
+
+func (r *fakeRetryInfo) GetRetryDelay() *fakeDuration { return r.delay }
+
+// codeError has a Code accessor, like errors from some RPC frameworks.
+type codeError struct {
+	code fakeCode
+}
+
+func (e codeError) Error() string  { return fmt.Sprintf("code %d", e.code) }
+func (e codeError) Code() fakeCode { return e.code }
+
+func TestStatusClassifier(t *testing.T) {
+	statusErr := func(code fakeCode, details ...any) error {
+		return &fakeStatusError{status: &fakeStatus{code: code, details: details}}
+	}
+	tests := []struct {
+		classifier StatusClassifier
+		err        error
+		want       errguard.Decision
+	}{
+		{err: nil, want: errguard.DecideStop()},
+		{err: errors.New("some error"), want: errguard.DecideStop()},
+		{err: statusErr(fakeUnavailable), want: errguard.DecideRetry()},
+		{err: statusErr(fakeAborted), want: errguard.DecideRetry()},
+		{err: statusErr(fakeResourceExhausted), want: errguard.DecideRetry()},
+		{err: statusErr(fakeNotFound), want: errguard.DecideStop()},
+		{err: statusErr(fakeOK), want: errguard.DecideStop()},
+		{err: statusErr(fakeDeadlineExceeded), want: errguard.DecideStop()},
+		{
+			classifier: StatusClassifier{RetryDeadlineExceeded: true},
+			err:        statusErr(fakeDeadlineExceeded),
+			want:       errguard.DecideRetry(),
+		},
+		{
+			err:  statusErr(fakeResourceExhausted, "other detail", &fakeRetryInfo{delay: &fakeDuration{d: 3 * time.Second}}),
+			want: errguard.DecideRetryAfter(3 * time.Second),
+		},
+		{
+			err:  statusErr(fakeUnavailable, &fakeRetryInfo{}),
+			want: errguard.DecideRetry(),
+		},
+		{err: &fakeStatusError{}, want: errguard.DecideStop()},
+		{err: (*fakeStatusError)(nil), want: errguard.DecideStop()},
+		{err: codeError{code: fakeUnavailable}, want: errguard.DecideRetry()},
+		{err: codeError{code: fakeNotFound}, want: errguard.DecideStop()},
+		{err: fmt.Errorf("calling service: %w", statusErr(fakeAborted)), want: errguard.DecideRetry()},
+	}
+
+	for i, tt := range tests {
+		if got := tt.classifier.Classify(tt.err); got != tt.want {
+			t.Errorf("%d: %v: got=%+v, want=%+v", i, tt.err, got, tt.want)
+		}
+		if got, want := tt.classifier.ShouldRetry(tt.err), tt.want.Retry; got != want {
+			t.Errorf("%d: %v: ShouldRetry: got=%v, want=%v", i, tt.err, got, want)
+		}
+	}
+}
@@ -0,0 +1,27 @@
+package errguard
+
+import (
+	"time"
+)
+
+// Clock provides the time to a guard. Tests can use a fake clock,
+// such as the one in package errguardtest, to avoid waiting.
+type Clock interface {
+	// Now returns the current time.
+	Now() time.Time
+
+	// After waits for duration d to elapse and then sends the
+	// current time on the returned channel.
+	After(d time.Duration) <-chan time.Time
+}
+
+// systemClock is the clock provided by the time package.
+type systemClock struct{}
+
+func (systemClock) Now() time.Time {
+	return time.Now()
+}
+
+func (systemClock) After(d time.Duration) <-chan time.Time {
+	return time.After(d)
+}
@@ -0,0 +1,49 @@
+package errguard
+
+import (
+	"context"
+)
+
+// Do calls function f and keeps retrying while it returns a
+// retryable error, using guard g. If g is nil, a guard with the
+// default settings is used.
+//
+// Do returns the value returned by the successful attempt. If it
+// returns an error, the value is the zero value of T, and never a
+// value returned by an earlier attempt.
+func Do[T any](ctx context.Context, g *Guard, f func(ctx context.Context) (T, error)) (T, error) {
+	if g == nil {
+		g = &Guard{}
+	}
+	var result T
+	err := g.do(ctx, func() error {
+		var err error
+		result, err = f(ctx)
+		return err
+	})
+	if err != nil {
+		var zero T
+		return zero, err
+	}
+	return result, nil
+}
+
+// Do2 is like Do, for functions that return two values.
+func Do2[T, U any](ctx context.Context, g *Guard, f func(ctx context.Context) (T, U, error)) (T, U, error) {
+	if g == nil {
+		g = &Guard{}
+	}
+	var result1 T
+	var result2 U
+	err := g.do(ctx, func() error {
+		var err error
+		result1, result2, err = f(ctx)
+		return err
+	})
+	if err != nil {
+		var zero1 T
+		var zero2 U
+		return zero1, zero2, err
+	}
+	return result1, result2, nil
+}
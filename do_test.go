@@ -0,0 +1,100 @@
+package errguard
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jjeffery/errguard/errguardtest"
+)
+
+func TestDo(t *testing.T) {
+	guard := &Guard{
+		Clock:       errguardtest.NewAutoClock(time.Now()),
+		MaxAttempts: 3,
+	}
+	testErr := errors.New("test error")
+
+	var attempt int
+	got, err := Do(context.Background(), guard, func(ctx context.Context) (string, error) {
+		attempt++
+		if attempt < 3 {
+			return "partial", Retry(testErr)
+		}
+		return "done", nil
+	})
+	if err != nil {
+		t.Fatalf("got=%v, want nil", err)
+	}
+	if want := "done"; got != want {
+		t.Errorf("got=%q, want=%q", got, want)
+	}
+
+	// stale results are not returned on failure
+	got, err = Do(context.Background(), guard, func(ctx context.Context) (string, error) {
+		return "stale", Retry(testErr)
+	})
+	if !errors.Is(err, testErr) {
+		t.Errorf("got=%v, want=%v", err, testErr)
+	}
+	if got != "" {
+		t.Errorf("got=%q, want zero value", got)
+	}
+
+	// nil guard uses the defaults
+	n, err := Do(context.Background(), nil, func(ctx context.Context) (int, error) {
+		return 42, nil
+	})
+	if n != 42 || err != nil {
+		t.Errorf("got=%v,%v, want=42,nil", n, err)
+	}
+}
+
+func TestDo2(t *testing.T) {
+	guard := &Guard{
+		Clock:       errguardtest.NewAutoClock(time.Now()),
+		MaxAttempts: 2,
+	}
+	var attempt int
+	s, n, err := Do2(context.Background(), guard, func(ctx context.Context) (string, int, error) {
+		attempt++
+		if attempt < 2 {
+			return "partial", 1, Retry(errors.New("test error"))
+		}
+		return "done", 2, nil
+	})
+	if s != "done" || n != 2 || err != nil {
+		t.Errorf("got=%v,%v,%v, want=done,2,nil", s, n, err)
+	}
+
+	s, n, err = Do2(context.Background(), guard, func(ctx context.Context) (string, int, error) {
+		return "stale", 3, Retry(errors.New("test error"))
+	})
+	if s != "" || n != 0 || err == nil {
+		t.Errorf("got=%v,%v,%v, want zero values and error", s, n, err)
+	}
+}
+
+func TestDoCaller(t *testing.T) {
+	logger := &errguardtest.Logger{}
+	guard := &Guard{
+		Clock:       errguardtest.NewAutoClock(time.Now()),
+		MaxAttempts: 2,
+		Logger:      logger,
+	}
+	Do(context.Background(), guard, func(ctx context.Context) (int, error) {
+		return 0, Retry(errors.New("test error"))
+	})
+	entries := logger.Entries()
+	if len(entries) == 0 {
+		t.Fatal("no messages logged")
+	}
+	for _, e := range entries {
+		if caller := e.Value("caller"); !strings.HasPrefix(fmt.Sprint(caller), "do_test.go:") {
+			t.Errorf("caller: got=%v, want do_test.go", caller)
+		}
+	}
+}
@@ -6,9 +6,6 @@ package errguard
 import (
 	"context"
 	"time"
-
-	"github.com/go-stack/stack"
-	"github.com/jjeffery/kv"
 )
 
 // Guard is used to retry error conditions that have a reasonable
@@ -16,12 +13,80 @@ import (
 type Guard struct {
 	// ShouldRetry returns true if the guard should retry
 	// after encountering error err. If nil, the default
-	// logic is used.
+	// logic is used. Ignored if Classifier is set.
 	ShouldRetry func(err error) bool
 
+	// Classifier decides whether and how the guard should
+	// retry after encountering an error. If nil, ShouldRetry
+	// is used.
+	Classifier Classifier
+
 	// If logger is set, the guard will log a message every
-	// time the guard encounters and error and retries.
+	// time the guard encounters and error and retries, when
+	// it gives up, and when it succeeds after retrying.
 	Logger Logger
+
+	// Backoff determines how long to wait before each retry.
+	// If nil, DefaultBackoff is used.
+	Backoff Backoff
+
+	// Jitter determines how the delay computed by the
+	// backoff is randomized. The default is no jitter.
+	Jitter Jitter
+
+	// Rand is the source of random numbers for applying
+	// jitter. If nil, the math/rand package functions are used.
+	Rand Rand
+
+	// MaxAttempts is the maximum number of times the guard
+	// will call the function. If zero there is no limit.
+	MaxAttempts int
+
+	// MaxElapsed is the maximum time the guard will spend
+	// retrying, measured from the start of the first attempt.
+	// The guard gives up rather than wait beyond this time.
+	// If zero there is no limit.
+	MaxElapsed time.Duration
+
+	// Budget, if set, limits the number of retries made by all
+	// guards that share the same budget.
+	Budget *Budget
+
+	// Breaker, if set, is a circuit breaker that prevents attempts
+	// while the operation keeps failing with retryable errors.
+	// State changes are logged to the guard's logger.
+	Breaker *Breaker
+
+	// Hooks are called as the guard runs.
+	// If nil, DefaultHooks is used.
+	Hooks *Hooks
+
+	// LogLevels determines the levels of the messages
+	// logged by the guard.
+	LogLevels LogLevels
+
+	// Clock provides the time for measuring elapsed time and
+	// waiting between attempts. The context deadline is also
+	// compared with the clock's time, as is the cooldown of the
+	// guard's Breaker unless it has its own clock. If nil, the
+	// system clock is used.
+	Clock Clock
+
+	// Name is the name of the operation, which is available to
+	// functions run by RunContext (see AttemptFromContext).
+	Name string
+
+	// AttemptTimeout, if set, limits the time allowed for each
+	// attempt run by RunContext. It has the same interface as
+	// Backoff, so the timeout can be constant, as with
+	// ConstantBackoff, or grow with each attempt, as with
+	// ExponentialBackoff.
+	AttemptTimeout Backoff
+
+	// PanicPolicy determines what the guard does when the
+	// function it is running panics. The default is to not
+	// recover the panic.
+	PanicPolicy PanicPolicy
 }
 
 // Retry wraps err to return an error that indicates
@@ -46,20 +111,114 @@ func (err retryT) Cause() error {
 	return err.cause
 }
 
+func (err retryT) Unwrap() error {
+	return err.cause
+}
+
 func (err retryT) ShouldRetry() bool {
 	return true
 }
 
+// RetryAfter wraps err to return an error that indicates the
+// operation should be retried by the guard after waiting for
+// duration d, instead of the delay computed by the guard's backoff.
+// This is useful when a server specifies how long to wait before
+// trying again. If err is nil returns nil.
+//
+// Any error in the chain with a RetryDelay() time.Duration method
+// is treated the same way.
+func RetryAfter(err error, d time.Duration) error {
+	if err == nil {
+		return nil
+	}
+	return retryAfterT{retryT: retryT{cause: err}, delay: d}
+}
+
+type retryAfterT struct {
+	retryT
+	delay time.Duration
+}
+
+func (err retryAfterT) RetryDelay() time.Duration {
+	return err.delay
+}
+
+type retryDelayer interface {
+	RetryDelay() time.Duration
+}
+
+// Permanent wraps err to return an error that indicates the
+// operation should never be retried by the guard, even if
+// another error in the chain indicates that it should be.
+// If err is nil returns nil.
+//
+// Any error in the chain with a Permanent() bool method that
+// returns true is treated the same way.
+func Permanent(err error) error {
+	if err == nil {
+		return nil
+	}
+	return permanentT{cause: err}
+}
+
+type permanentT struct {
+	cause error
+}
+
+func (err permanentT) Error() string {
+	return err.cause.Error()
+}
+
+func (err permanentT) Cause() error {
+	return err.cause
+}
+
+func (err permanentT) Unwrap() error {
+	return err.cause
+}
+
+func (err permanentT) Permanent() bool {
+	return true
+}
+
+// IsPermanent reports whether any error in err's chain has been
+// marked as permanent, and so should never be retried.
+func IsPermanent(err error) bool {
+	type permanenter interface {
+		Permanent() bool
+	}
+	return WalkChain(err, func(err error) bool {
+		p, ok := err.(permanenter)
+		return ok && p.Permanent()
+	})
+}
+
 var (
 	// ShouldRetry is the default test for whether
 	// a guard should retry after encountering error err.
 	// This value is used if not specified for an individual
 	// guard.
+	//
+	// The default logic returns false for permanent errors
+	// (see Permanent). Otherwise it searches the error chain,
+	// including errors joined with errors.Join and errors with
+	// a Cause method, for the first error with a ShouldRetry() bool
+	// method and returns its result.
 	ShouldRetry func(err error) bool
 
 	// DefaultLogger is the default logger to use if
 	// not specified for an individual guard.
 	DefaultLogger Logger
+
+	// DefaultBackoff is the default backoff to use if
+	// not specified for an individual guard. It waits 100ms
+	// after the first attempt and doubles the wait after
+	// each subsequent attempt.
+	DefaultBackoff Backoff
+
+	// DefaultHooks are the hooks called if not specified
+	// for an individual guard. If nil, no hooks are called.
+	DefaultHooks *Hooks
 )
 
 func init() {
@@ -67,60 +226,44 @@ func init() {
 		type shouldRetryer interface {
 			ShouldRetry() bool
 		}
-		if shouldRetry, ok := err.(shouldRetryer); ok {
+		if IsPermanent(err) {
+			return false
+		}
+		if shouldRetry, ok := findInChain[shouldRetryer](err); ok {
 			return shouldRetry.ShouldRetry()
 		}
 		return false
 	}
 
 	DefaultLogger = noopLogger{}
+	DefaultBackoff = ExponentialBackoff{}
 }
 
 // Run function f and keep retrying while it returns
 // a retryable error.
+//
+// If the guard gives up because it has reached MaxAttempts or
+// MaxElapsed, because its Budget is exhausted or its Breaker is
+// open, or because the context is done or its deadline would pass
+// before the next attempt, it returns a *RetryError that wraps the
+// error returned by the last attempt. When the context is done, the
+// error also wraps ctx.Err() and context.Cause(ctx), so
+// errors.Is(err, context.DeadlineExceeded) works as expected.
 func (g *Guard) Run(ctx context.Context, f func() error) error {
-	shouldRetry := g.ShouldRetry
-	if shouldRetry == nil {
-		shouldRetry = ShouldRetry
-	}
-
-	logger := g.Logger
-	if logger == nil {
-		logger = DefaultLogger
-	}
-
-	sleepDuration := time.Millisecond * 100
-	for attempt := 1; ; attempt++ {
-		err := f()
-		if err == nil {
-			return nil
-		}
-		if !shouldRetry(err) {
-			return err
-		}
-
-		// At this point an optimistic locking exception has occurred.
-		// Log a message and if there is still time, wait and retry.
-		var level string
-		if attempt <= 1 {
-			level = "info"
-		} else {
-			level = "warn"
-		}
-
-		keyvals := []interface{}{
-			kv.P("level", level),
-			err,
-			kv.P("caller", stack.Caller(1)),
-			kv.P("attempt", attempt),
-		}
-		logger.Log(kv.Flatten(keyvals)...)
+	return g.do(ctx, f)
+}
 
-		select {
-		case <-ctx.Done():
-			return ctx.Err()
-		case <-time.After(sleepDuration):
-			sleepDuration = sleepDuration * 2
+// do implements Run. It must be called directly by exported
+// functions so that the caller logged is the caller of the
+// exported function.
+func (g *Guard) do(ctx context.Context, f func() error) error {
+	r := g.start(ctx, 2)
+	defer r.abandon()
+	f = g.recoverPanics(r.logger, f)
+	for r.Next() {
+		if !r.Retry(f()) {
+			break
 		}
 	}
+	return r.Err()
 }
@@ -2,8 +2,13 @@ package errguard
 
 import (
 	"context"
+	stderrors "errors"
+	"fmt"
+	"strings"
 	"testing"
+	"time"
 
+	"github.com/jjeffery/errguard/errguardtest"
 	"github.com/jjeffery/errors"
 	"github.com/jjeffery/kv"
 )
@@ -28,6 +33,140 @@ func TestRetry(t *testing.T) {
 	}
 }
 
+func TestShouldRetryChain(t *testing.T) {
+	base := stderrors.New("serialization failure")
+	tests := []struct {
+		err  error
+		want bool
+	}{
+		{err: nil, want: false},
+		{err: base, want: false},
+		{err: Retry(base), want: true},
+		{err: fmt.Errorf("saving order: %w", Retry(base)), want: true},
+		{err: fmt.Errorf("saving order: %w", fmt.Errorf("tx: %w", Retry(base))), want: true},
+		{err: stderrors.Join(stderrors.New("other"), Retry(base)), want: true},
+		{err: fmt.Errorf("saving order: %w", stderrors.Join(base)), want: false},
+		{err: causer{Retry(base)}, want: true},
+		{err: fmt.Errorf("wrapped: %w", causer{Retry(base)}), want: true},
+		{err: causer{nil}, want: false},
+		{err: noRetry{Retry(base)}, want: false},
+	}
+	for i, tt := range tests {
+		if got := ShouldRetry(tt.err); got != tt.want {
+			t.Errorf("%d: %v: got=%v, want=%v", i, tt.err, got, tt.want)
+		}
+	}
+
+	if got, want := stderrors.Unwrap(Retry(base)), base; got != want {
+		t.Errorf("Unwrap: got=%v, want=%v", got, want)
+	}
+	if !stderrors.Is(fmt.Errorf("wrapped: %w", Retry(base)), base) {
+		t.Errorf("want errors.Is(err, base)")
+	}
+}
+
+// causer is an error in the style of github.com/pkg/errors.
+type causer struct {
+	cause error
+}
+
+func (c causer) Error() string { return "causer" }
+func (c causer) Cause() error  { return c.cause }
+
+// noRetry overrides the retryability of the error it wraps.
+type noRetry struct {
+	error
+}
+
+func (e noRetry) ShouldRetry() bool { return false }
+func (e noRetry) Unwrap() error     { return e.error }
+
+func TestRetryAfter(t *testing.T) {
+	base := stderrors.New("too many requests")
+	err := RetryAfter(base, time.Second)
+	if !ShouldRetry(err) {
+		t.Errorf("want ShouldRetry=true, got false")
+	}
+	if !stderrors.Is(err, base) {
+		t.Errorf("want errors.Is(err, base)")
+	}
+	if got, want := err.Error(), base.Error(); got != want {
+		t.Errorf("got=%q, want=%q", got, want)
+	}
+	if got := RetryAfter(nil, time.Second); got != nil {
+		t.Errorf("got %v, want nil", got)
+	}
+
+	// the guard gives up at once unless it uses the retry delay
+	guard := Guard{
+		Backoff:    ConstantBackoff{Interval: time.Hour},
+		MaxElapsed: time.Minute,
+	}
+	var attempt int
+	err = guard.Run(context.Background(), func() error {
+		attempt++
+		if attempt < 3 {
+			return fmt.Errorf("calling service: %w", RetryAfter(base, 5*time.Millisecond))
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("got=%v, want nil", err)
+	}
+}
+
+func TestPermanent(t *testing.T) {
+	base := stderrors.New("validation failed")
+	tests := []struct {
+		err           error
+		wantPermanent bool
+	}{
+		{err: nil},
+		{err: base},
+		{err: Retry(base)},
+		{err: Permanent(base), wantPermanent: true},
+		{err: Retry(Permanent(base)), wantPermanent: true},
+		{err: Permanent(Retry(base)), wantPermanent: true},
+		{err: fmt.Errorf("tx: %w", Retry(fmt.Errorf("commit: %w", Permanent(base)))), wantPermanent: true},
+		{err: stderrors.Join(Retry(base), Permanent(base)), wantPermanent: true},
+	}
+	for i, tt := range tests {
+		if got, want := IsPermanent(tt.err), tt.wantPermanent; got != want {
+			t.Errorf("%d: IsPermanent: got=%v, want=%v", i, got, want)
+		}
+		if tt.wantPermanent && ShouldRetry(tt.err) {
+			t.Errorf("%d: want ShouldRetry=false, got true", i)
+		}
+	}
+
+	perm := Permanent(base)
+	if !stderrors.Is(perm, base) {
+		t.Errorf("want errors.Is(err, base)")
+	}
+	if got, want := perm.Error(), base.Error(); got != want {
+		t.Errorf("got=%q, want=%q", got, want)
+	}
+	if got := Permanent(nil); got != nil {
+		t.Errorf("got %v, want nil", got)
+	}
+
+	// permanent takes precedence over a custom ShouldRetry
+	guard := Guard{
+		ShouldRetry: func(err error) bool { return true },
+	}
+	var attempt int
+	err := guard.Run(context.Background(), func() error {
+		attempt++
+		return Retry(perm)
+	})
+	if got, want := attempt, 1; got != want {
+		t.Errorf("attempt: got=%v, want=%v", got, want)
+	}
+	if !stderrors.Is(err, base) {
+		t.Errorf("got=%v, want %v", err, base)
+	}
+}
+
 func TestGuard(t *testing.T) {
 	var logs []kv.List
 	var attempt int
@@ -44,11 +183,22 @@ func TestGuard(t *testing.T) {
 		return nil
 	})
 
-	if got, want := len(logs), 2; got != want {
-		t.Errorf("got=%v, want=%v", got, want)
+	if got, want := len(logs), 3; got != want {
+		t.Fatalf("got=%v, want=%v", got, want)
+	}
+
+	// last message reports success after retrying
+	if got, want := getString(logs[2], "msg"), "succeeded after retrying"; got != want {
+		t.Errorf("msg: got=%v, want=%v", got, want)
+	}
+	if got, want := getString(logs[2], "level"), "info"; got != want {
+		t.Errorf("level: got=%v, want=%v", got, want)
+	}
+	if got, want := getInt(logs[2], "attempts"), 3; got != want {
+		t.Errorf("attempts: got=%v, want=%v", got, want)
 	}
 
-	for i, l := range logs {
+	for i, l := range logs[:2] {
 		if got, want := getInt(l, "attempt"), i+1; got != want {
 			t.Errorf("attempt: got=%v, want=%v", got, want)
 		}
@@ -64,6 +214,185 @@ func TestGuard(t *testing.T) {
 	}
 }
 
+func TestGuardLogLevels(t *testing.T) {
+	var levels []string
+	guard := Guard{
+		Backoff:     ConstantBackoff{Interval: time.Millisecond},
+		MaxAttempts: 4,
+		LogLevels: LogLevels{
+			WarnAfter: 2,
+			GiveUp:    "warn",
+		},
+		Logger: loggerFunc(func(v ...interface{}) error {
+			l := kv.List(v)
+			levels = append(levels, getString(l, "msg")+":"+getString(l, "level"))
+			return nil
+		}),
+	}
+	guard.Run(context.Background(), func() error {
+		return Retry(errors.New("test error"))
+	})
+	want := []string{
+		"retrying:info",
+		"retrying:info",
+		"retrying:warn",
+		"giving up:warn",
+	}
+	if got := strings.Join(levels, ","); got != strings.Join(want, ",") {
+		t.Errorf("got=%v, want=%v", levels, want)
+	}
+
+	// default levels
+	levels = nil
+	guard.LogLevels = LogLevels{}
+	guard.Run(context.Background(), func() error {
+		return Retry(errors.New("test error"))
+	})
+	want = []string{
+		"retrying:info",
+		"retrying:warn",
+		"retrying:warn",
+		"giving up:error",
+	}
+	if got := strings.Join(levels, ","); got != strings.Join(want, ",") {
+		t.Errorf("got=%v, want=%v", levels, want)
+	}
+}
+
+func TestGuardMaxAttempts(t *testing.T) {
+	guard := Guard{
+		Backoff:     ConstantBackoff{Interval: time.Millisecond},
+		MaxAttempts: 3,
+	}
+	testErr := errors.New("test error")
+	var attempt int
+	err := guard.Run(context.Background(), func() error {
+		attempt++
+		return Retry(testErr)
+	})
+	if got, want := attempt, 3; got != want {
+		t.Errorf("attempt: got=%v, want=%v", got, want)
+	}
+	var retryErr *RetryError
+	if !stderrors.As(err, &retryErr) {
+		t.Fatalf("got=%T, want *RetryError", err)
+	}
+	if got, want := retryErr.Attempts, 3; got != want {
+		t.Errorf("Attempts: got=%v, want=%v", got, want)
+	}
+	if !stderrors.Is(err, ErrMaxAttempts) {
+		t.Errorf("want errors.Is(err, ErrMaxAttempts)")
+	}
+	if got, want := errors.Cause(retryErr.Last), testErr; got != want {
+		t.Errorf("Last: got=%v, want=%v", got, want)
+	}
+	if got, want := err.Error(), "errguard: maximum attempts reached after 3 attempts: test error"; got != want {
+		t.Errorf("got=%q, want=%q", got, want)
+	}
+}
+
+func TestGuardMaxElapsed(t *testing.T) {
+	guard := Guard{
+		Clock:      errguardtest.NewAutoClock(time.Now()),
+		Backoff:    ConstantBackoff{Interval: 20 * time.Millisecond},
+		MaxElapsed: 50 * time.Millisecond,
+	}
+	var attempt int
+	err := guard.Run(context.Background(), func() error {
+		attempt++
+		return Retry(errors.New("test error"))
+	})
+	if got, want := attempt, 3; got != want {
+		t.Errorf("attempt: got=%v, want=%v", got, want)
+	}
+	if !stderrors.Is(err, ErrMaxElapsed) {
+		t.Errorf("want errors.Is(err, ErrMaxElapsed), got %v", err)
+	}
+}
+
+func TestGuardContextDone(t *testing.T) {
+	testErr := errors.New("deadlock detected")
+	guard := Guard{
+		Backoff: ConstantBackoff{Interval: time.Hour},
+	}
+
+	t.Run("deadline", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+		defer cancel()
+		err := guard.Run(ctx, func() error {
+			return Retry(testErr)
+		})
+		if !stderrors.Is(err, ErrContextDeadline) {
+			t.Errorf("want errors.Is(err, ErrContextDeadline), got %v", err)
+		}
+		if !stderrors.Is(err, context.DeadlineExceeded) {
+			t.Errorf("want errors.Is(err, context.DeadlineExceeded), got %v", err)
+		}
+		var retryErr *RetryError
+		if !stderrors.As(err, &retryErr) {
+			t.Fatalf("got=%T, want *RetryError", err)
+		}
+		if got, want := retryErr.Attempts, 1; got != want {
+			t.Errorf("Attempts: got=%v, want=%v", got, want)
+		}
+		if got, want := errors.Cause(retryErr.Last), testErr; got != want {
+			t.Errorf("Last: got=%v, want=%v", got, want)
+		}
+	})
+
+	t.Run("deadline during wait", func(t *testing.T) {
+		// The deadline is compared with the fake clock's time, so
+		// the test does not depend on real time passing.
+		start := time.Now()
+		clock := errguardtest.NewAutoClock(start)
+		guard := Guard{
+			Backoff: ConstantBackoff{Interval: 5 * time.Millisecond},
+			Clock:   clock,
+		}
+		ctx, cancel := context.WithDeadline(context.Background(), start.Add(time.Hour))
+		defer cancel()
+		var attempt int
+		err := guard.Run(ctx, func() error {
+			attempt++
+			if attempt == 3 {
+				// slow attempt leaves less time than the next wait
+				clock.Advance(start.Add(time.Hour - 2*time.Millisecond).Sub(clock.Now()))
+			}
+			return Retry(testErr)
+		})
+		if !stderrors.Is(err, ErrContextDeadline) {
+			t.Errorf("want errors.Is(err, ErrContextDeadline), got %v", err)
+		}
+		if got, want := attempt, 3; got != want {
+			t.Errorf("attempt: got=%v, want=%v", got, want)
+		}
+		if ctx.Err() != nil {
+			t.Errorf("want guard to give up before the deadline")
+		}
+	})
+
+	t.Run("cause", func(t *testing.T) {
+		causeErr := stderrors.New("shutting down")
+		ctx, cancel := context.WithCancelCause(context.Background())
+		go func() {
+			time.Sleep(10 * time.Millisecond)
+			cancel(causeErr)
+		}()
+		err := guard.Run(ctx, func() error {
+			return Retry(testErr)
+		})
+		if !stderrors.Is(err, context.Canceled) {
+			t.Errorf("want errors.Is(err, context.Canceled), got %v", err)
+		}
+		if !stderrors.Is(err, causeErr) {
+			t.Errorf("want errors.Is(err, causeErr), got %v", err)
+		}
+		if got, want := err.Error(), "context canceled: shutting down after 1 attempt: deadlock detected"; got != want {
+			t.Errorf("got=%q, want=%q", got, want)
+		}
+	})
+}
+
 func getInt(list kv.List, key string) int {
 	for i := 0; i < len(list); i += 2 {
 		if list[i] == key {
@@ -80,3 +409,79 @@ func getString(list kv.List, key string) string {
 	}
 	return "<not-found>"
 }
+
+func TestRetryErrorHistory(t *testing.T) {
+	errDeadlock := stderrors.New("deadlock")
+	errTimeout := stderrors.New("timeout")
+	guard := Guard{
+		Backoff:     ConstantBackoff{Interval: time.Millisecond},
+		MaxAttempts: 4,
+	}
+	start := time.Now()
+	var attempt int
+	err := guard.Run(context.Background(), func() error {
+		attempt++
+		if attempt == 1 {
+			return Retry(errDeadlock)
+		}
+		return Retry(errTimeout)
+	})
+
+	var retryErr *RetryError
+	if !stderrors.As(err, &retryErr) {
+		t.Fatalf("got=%T, want *RetryError", err)
+	}
+	if !stderrors.Is(err, errDeadlock) || !stderrors.Is(err, errTimeout) {
+		t.Errorf("want errors.Is to match all attempt errors")
+	}
+	if got, want := len(retryErr.History), 4; got != want {
+		t.Fatalf("history: got=%v, want=%v", got, want)
+	}
+	for i, rec := range retryErr.History {
+		if got, want := rec.Number, i+1; got != want {
+			t.Errorf("%d: number: got=%v, want=%v", i, got, want)
+		}
+		if rec.Start.Before(start) {
+			t.Errorf("%d: start: got=%v, want after %v", i, rec.Start, start)
+		}
+		wantDelay := time.Millisecond
+		if i == 3 {
+			wantDelay = 0
+		}
+		if got := rec.Delay; got != wantDelay {
+			t.Errorf("%d: delay: got=%v, want=%v", i, got, wantDelay)
+		}
+	}
+	if got, want := errors.Cause(retryErr.History[0].Err), errDeadlock; got != want {
+		t.Errorf("first: got=%v, want=%v", got, want)
+	}
+	if got, want := errors.Cause(retryErr.History[3].Err), errTimeout; got != want {
+		t.Errorf("last: got=%v, want=%v", got, want)
+	}
+}
+
+func TestRetryErrorHistoryBounded(t *testing.T) {
+	guard := Guard{
+		Backoff:     ConstantBackoff{Interval: time.Microsecond},
+		MaxAttempts: MaxHistory + 5,
+	}
+	err := guard.Run(context.Background(), func() error {
+		return Retry(stderrors.New("test error"))
+	})
+	var retryErr *RetryError
+	if !stderrors.As(err, &retryErr) {
+		t.Fatalf("got=%T, want *RetryError", err)
+	}
+	if got, want := len(retryErr.History), MaxHistory; got != want {
+		t.Fatalf("history: got=%v, want=%v", got, want)
+	}
+	if got, want := retryErr.History[0].Number, 1; got != want {
+		t.Errorf("first: got=%v, want=%v", got, want)
+	}
+	if got, want := retryErr.History[1].Number, 7; got != want {
+		t.Errorf("second: got=%v, want=%v", got, want)
+	}
+	if got, want := retryErr.History[MaxHistory-1].Number, MaxHistory+5; got != want {
+		t.Errorf("last: got=%v, want=%v", got, want)
+	}
+}
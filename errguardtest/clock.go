@@ -0,0 +1,106 @@
+package errguardtest
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// Clock is a fake clock for use with errguard.Guard. Its time only
+// changes when it is advanced, either manually by calling Advance,
+// or automatically whenever the guard waits.
+type Clock struct {
+	mutex   sync.Mutex
+	cond    *sync.Cond
+	now     time.Time
+	auto    bool
+	waiters []*waiter
+	waits   []time.Duration
+}
+
+type waiter struct {
+	until time.Time
+	ch    chan time.Time
+}
+
+// NewClock returns a clock set to time now, which only changes
+// when Advance is called. Because the guard blocks while waiting,
+// Advance must be called from another goroutine.
+func NewClock(now time.Time) *Clock {
+	c := &Clock{now: now}
+	c.cond = sync.NewCond(&c.mutex)
+	return c
+}
+
+// NewAutoClock returns a clock set to time now, which advances
+// immediately by the requested duration whenever the guard waits.
+// This allows a guard to run in the test goroutine without waiting.
+func NewAutoClock(now time.Time) *Clock {
+	c := NewClock(now)
+	c.auto = true
+	return c
+}
+
+// Now returns the clock's current time.
+func (c *Clock) Now() time.Time {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return c.now
+}
+
+// After returns a channel that receives the clock's time once
+// the clock has advanced by duration d.
+func (c *Clock) After(d time.Duration) <-chan time.Time {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.waits = append(c.waits, d)
+	ch := make(chan time.Time, 1)
+	if c.auto {
+		c.now = c.now.Add(d)
+	}
+	if d <= 0 || c.auto {
+		ch <- c.now
+		return ch
+	}
+	c.waiters = append(c.waiters, &waiter{until: c.now.Add(d), ch: ch})
+	c.cond.Broadcast()
+	return ch
+}
+
+// Advance moves the clock forward by duration d, notifying
+// any waiters whose time has come.
+func (c *Clock) Advance(d time.Duration) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.now = c.now.Add(d)
+	sort.Slice(c.waiters, func(i, j int) bool {
+		return c.waiters[i].until.Before(c.waiters[j].until)
+	})
+	var remaining []*waiter
+	for _, w := range c.waiters {
+		if w.until.After(c.now) {
+			remaining = append(remaining, w)
+			continue
+		}
+		w.ch <- c.now
+	}
+	c.waiters = remaining
+}
+
+// BlockUntil blocks until at least n goroutines are waiting
+// for the clock to advance.
+func (c *Clock) BlockUntil(n int) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	for len(c.waiters) < n {
+		c.cond.Wait()
+	}
+}
+
+// Waits returns the durations of all waits requested
+// from the clock, in order.
+func (c *Clock) Waits() []time.Duration {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return append([]time.Duration(nil), c.waits...)
+}
@@ -0,0 +1,47 @@
+// Package errguardtest provides utilities for testing code
+// that uses package errguard, without waiting in real time.
+package errguardtest
+
+import (
+	"sync"
+)
+
+// Flaky is a scripted function that returns each of its errors
+// in turn, and then succeeds.
+type Flaky struct {
+	// Errs are the errors returned by successive calls.
+	Errs []error
+
+	mutex sync.Mutex
+	calls int
+}
+
+// NewFlaky returns a function that fails n times with err,
+// and then succeeds.
+func NewFlaky(n int, err error) *Flaky {
+	f := &Flaky{}
+	for i := 0; i < n; i++ {
+		f.Errs = append(f.Errs, err)
+	}
+	return f
+}
+
+// Run returns the next scripted error, or nil if
+// there are none left. It is suitable for passing to
+// errguard.Guard.Run.
+func (f *Flaky) Run() error {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	f.calls++
+	if f.calls <= len(f.Errs) {
+		return f.Errs[f.calls-1]
+	}
+	return nil
+}
+
+// Calls returns the number of times Run has been called.
+func (f *Flaky) Calls() int {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	return f.calls
+}
@@ -0,0 +1,115 @@
+package errguardtest_test
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/jjeffery/errguard"
+	"github.com/jjeffery/errguard/errguardtest"
+)
+
+var epoch = time.Date(2017, 1, 2, 3, 4, 5, 0, time.UTC)
+
+func TestAutoClock(t *testing.T) {
+	clock := errguardtest.NewAutoClock(epoch)
+	logger := &errguardtest.Logger{}
+	flaky := errguardtest.NewFlaky(3, errguard.Retry(errors.New("deadlock")))
+	guard := errguard.Guard{
+		Clock:  clock,
+		Logger: logger,
+	}
+
+	if err := guard.Run(context.Background(), flaky.Run); err != nil {
+		t.Fatalf("got=%v, want nil", err)
+	}
+	if got, want := flaky.Calls(), 4; got != want {
+		t.Errorf("calls: got=%v, want=%v", got, want)
+	}
+	wantWaits := []time.Duration{100 * time.Millisecond, 200 * time.Millisecond, 400 * time.Millisecond}
+	if got := clock.Waits(); !slices.Equal(got, wantWaits) {
+		t.Errorf("waits: got=%v, want=%v", got, wantWaits)
+	}
+	if got, want := clock.Now(), epoch.Add(700*time.Millisecond); !got.Equal(want) {
+		t.Errorf("now: got=%v, want=%v", got, want)
+	}
+	logger.AssertAttempts(t, 1, 2, 3)
+	logger.AssertLevels(t, "info", "warn", "warn", "info")
+	if got, want := logger.Entries()[3].Msg, "succeeded after retrying"; got != want {
+		t.Errorf("msg: got=%v, want=%v", got, want)
+	}
+}
+
+func TestAutoClockMaxElapsed(t *testing.T) {
+	clock := errguardtest.NewAutoClock(epoch)
+	flaky := errguardtest.NewFlaky(100, errguard.Retry(errors.New("deadlock")))
+	guard := errguard.Guard{
+		Clock:      clock,
+		Backoff:    errguard.ConstantBackoff{Interval: time.Second},
+		MaxElapsed: 5 * time.Second,
+	}
+	err := guard.Run(context.Background(), flaky.Run)
+	if !errors.Is(err, errguard.ErrMaxElapsed) {
+		t.Errorf("got=%v, want=%v", err, errguard.ErrMaxElapsed)
+	}
+	if got, want := flaky.Calls(), 6; got != want {
+		t.Errorf("calls: got=%v, want=%v", got, want)
+	}
+}
+
+func TestManualClock(t *testing.T) {
+	clock := errguardtest.NewClock(epoch)
+	flaky := &errguardtest.Flaky{Errs: []error{
+		errguard.Retry(errors.New("one")),
+		errguard.Retry(errors.New("two")),
+	}}
+	guard := errguard.Guard{
+		Clock:   clock,
+		Backoff: errguard.ConstantBackoff{Interval: time.Minute},
+	}
+
+	done := make(chan error)
+	go func() {
+		done <- guard.Run(context.Background(), flaky.Run)
+	}()
+
+	for i := 0; i < 2; i++ {
+		clock.BlockUntil(1)
+		clock.Advance(30 * time.Second)
+		select {
+		case <-done:
+			t.Fatalf("guard did not wait")
+		default:
+		}
+		clock.Advance(30 * time.Second)
+	}
+	if err := <-done; err != nil {
+		t.Fatalf("got=%v, want nil", err)
+	}
+	if got, want := flaky.Calls(), 3; got != want {
+		t.Errorf("calls: got=%v, want=%v", got, want)
+	}
+}
+
+func TestLogger(t *testing.T) {
+	logger := &errguardtest.Logger{}
+	logger.Log("level", "warn", "msg", "retrying", "attempt", 2)
+	entries := logger.Entries()
+	if got, want := len(entries), 1; got != want {
+		t.Fatalf("got=%v, want=%v", got, want)
+	}
+	if got, want := entries[0].Level, "warn"; got != want {
+		t.Errorf("level: got=%v, want=%v", got, want)
+	}
+	if got, want := entries[0].Value("attempt"), 2; got != want {
+		t.Errorf("attempt: got=%v, want=%v", got, want)
+	}
+	if got := entries[0].Value("missing"); got != nil {
+		t.Errorf("missing: got=%v, want nil", got)
+	}
+	logger.Reset()
+	logger.AssertLevels(t)
+	logger.AssertAttempts(t)
+}
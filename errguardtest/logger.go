@@ -0,0 +1,101 @@
+package errguardtest
+
+import (
+	"slices"
+	"sync"
+	"testing"
+)
+
+// Logger is an errguard.Logger that records the messages
+// logged by a guard.
+type Logger struct {
+	mutex   sync.Mutex
+	entries []Entry
+}
+
+// Entry is a message recorded by a Logger.
+type Entry struct {
+	Level   string
+	Msg     string
+	Keyvals []interface{}
+}
+
+// Value returns the value of key in the entry's key/value pairs,
+// or nil if it is not present.
+func (e Entry) Value(key string) interface{} {
+	for i := 0; i+1 < len(e.Keyvals); i += 2 {
+		if e.Keyvals[i] == key {
+			return e.Keyvals[i+1]
+		}
+	}
+	return nil
+}
+
+// Log implements the errguard.Logger interface.
+func (l *Logger) Log(v ...interface{}) error {
+	entry := Entry{Keyvals: append([]interface{}(nil), v...)}
+	if level, ok := entry.Value("level").(string); ok {
+		entry.Level = level
+	}
+	if msg, ok := entry.Value("msg").(string); ok {
+		entry.Msg = msg
+	}
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	l.entries = append(l.entries, entry)
+	return nil
+}
+
+// Entries returns the recorded messages.
+func (l *Logger) Entries() []Entry {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	return append([]Entry(nil), l.entries...)
+}
+
+// Levels returns the level of each recorded message.
+func (l *Logger) Levels() []string {
+	var levels []string
+	for _, e := range l.Entries() {
+		levels = append(levels, e.Level)
+	}
+	return levels
+}
+
+// Attempts returns the attempt number of each recorded
+// message that has one, which are the messages logged
+// before retrying.
+func (l *Logger) Attempts() []int {
+	var attempts []int
+	for _, e := range l.Entries() {
+		if attempt, ok := e.Value("attempt").(int); ok {
+			attempts = append(attempts, attempt)
+		}
+	}
+	return attempts
+}
+
+// AssertLevels reports an error if the levels of the
+// recorded messages are not want.
+func (l *Logger) AssertLevels(t testing.TB, want ...string) {
+	t.Helper()
+	if got := l.Levels(); !slices.Equal(got, want) {
+		t.Errorf("levels: got=%v, want=%v", got, want)
+	}
+}
+
+// AssertAttempts reports an error if the attempt numbers of
+// the recorded messages are not want.
+func (l *Logger) AssertAttempts(t testing.TB, want ...int) {
+	t.Helper()
+	if got := l.Attempts(); !slices.Equal(got, want) {
+		t.Errorf("attempts: got=%v, want=%v", got, want)
+	}
+}
+
+// Reset discards the recorded messages.
+func (l *Logger) Reset() {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	l.entries = nil
+}
@@ -0,0 +1,121 @@
+package errguard
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+var (
+	// ErrMaxAttempts is the reason a guard gives up when it
+	// has reached its maximum number of attempts.
+	ErrMaxAttempts = errors.New("errguard: maximum attempts reached")
+
+	// ErrMaxElapsed is the reason a guard gives up when another
+	// attempt would exceed its maximum elapsed time.
+	ErrMaxElapsed = errors.New("errguard: maximum elapsed time reached")
+
+	// ErrContextDeadline is the reason a guard gives up when
+	// waiting before the next attempt would exceed the context
+	// deadline. It wraps context.DeadlineExceeded.
+	ErrContextDeadline = fmt.Errorf("errguard: retry would exceed context deadline: %w", context.DeadlineExceeded)
+
+	// ErrBudgetExhausted is the reason a guard gives up when
+	// its retry budget has been exhausted.
+	ErrBudgetExhausted = errors.New("errguard: retry budget exhausted")
+
+	// ErrBreakerOpen is the reason a guard fails without making
+	// an attempt when its circuit breaker is open.
+	ErrBreakerOpen = errors.New("errguard: circuit breaker is open")
+
+	// ErrNotReported is the error for a retry loop that moved on
+	// to the next attempt without reporting the result of the
+	// previous attempt by calling Retrier.Retry.
+	ErrNotReported = errors.New("errguard: attempt result not reported")
+)
+
+// RetryError is returned by a guard when it gives up retrying
+// an operation that failed with a retryable error.
+//
+// The reason and the errors of all attempts in the history are
+// available to errors.Is and errors.As, so errors.Is(err, ErrMaxAttempts)
+// reports whether the guard gave up because of its attempt limit.
+type RetryError struct {
+	// Reason describes why the guard gave up, eg ErrMaxAttempts.
+	Reason error
+
+	// Last is the error returned by the last attempt, or nil
+	// if no attempt was made.
+	Last error
+
+	// Attempts is the number of attempts made.
+	Attempts int
+
+	// Elapsed is the time elapsed since the first attempt started.
+	Elapsed time.Duration
+
+	// History contains details of the failed attempts, in order.
+	// If there were more than MaxHistory attempts, it contains the
+	// first attempt followed by the most recent attempts.
+	History []AttemptRecord
+}
+
+// MaxHistory is the maximum number of attempts recorded in the
+// history of a RetryError.
+const MaxHistory = 10
+
+// AttemptRecord contains details of a failed attempt.
+type AttemptRecord struct {
+	Number int           // Attempt number, starting at 1
+	Err    error         // Error returned by the attempt
+	Start  time.Time     // Time the attempt started
+	Delay  time.Duration // Time waited before the next attempt, if any
+}
+
+func (e *RetryError) Error() string {
+	if e.Last == nil {
+		return e.Reason.Error()
+	}
+	attempts := "attempts"
+	if e.Attempts == 1 {
+		attempts = "attempt"
+	}
+	return fmt.Sprintf("%v after %d %s: %v", e.Reason, e.Attempts, attempts, e.Last)
+}
+
+// Unwrap returns the reason and the errors of the attempts in
+// the history. If there is no history, it returns the reason and
+// the last error.
+func (e *RetryError) Unwrap() []error {
+	errs := []error{e.Reason}
+	for _, a := range e.History {
+		errs = append(errs, a.Err)
+	}
+	if len(e.History) == 0 && e.Last != nil {
+		errs = append(errs, e.Last)
+	}
+	return errs
+}
+
+// appendHistory appends rec to history, keeping the first attempt
+// and the most recent attempts up to a total of MaxHistory.
+func appendHistory(history []AttemptRecord, rec AttemptRecord) []AttemptRecord {
+	if len(history) < MaxHistory {
+		return append(history, rec)
+	}
+	copy(history[1:], history[2:])
+	history[len(history)-1] = rec
+	return history
+}
+
+// contextReason returns the reason for giving up when ctx is done.
+// If the context was cancelled with a cause, the reason wraps both
+// ctx.Err() and the cause.
+func contextReason(ctx context.Context) error {
+	err := ctx.Err()
+	if cause := context.Cause(ctx); cause != nil && cause != err {
+		return fmt.Errorf("%w: %w", err, cause)
+	}
+	return err
+}
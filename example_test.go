@@ -10,10 +10,7 @@ import (
 func Example() {
 	ctx := context.TODO()
 	var guard Guard
-	guard.Logger = loggerFunc(func(v ...interface{}) error {
-		log.Println(v...)
-		return nil
-	})
+	guard.Logger = NewStdLogger(log.Default())
 
 	guard.Run(ctx, func() error {
 		return doSomethingWith(ctx)
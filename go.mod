@@ -0,0 +1,8 @@
+module github.com/jjeffery/errguard
+
+go 1.23
+
+require (
+	github.com/go-stack/stack v1.8.1
+	github.com/spf13/pflag v1.0.5
+)
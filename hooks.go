@@ -0,0 +1,47 @@
+package errguard
+
+import (
+	"time"
+)
+
+// Hooks contains functions that are called as a guard runs,
+// which are useful for recording metrics, tracing and auditing.
+// Any of the functions can be nil.
+type Hooks struct {
+	// OnAttempt is called before each attempt. The first attempt is 1.
+	OnAttempt func(attempt int)
+
+	// OnRetry is called when an attempt has failed with error err,
+	// and the guard will wait for delay before the next attempt.
+	OnRetry func(attempt int, err error, delay time.Duration)
+
+	// OnGiveUp is called when the guard returns error err.
+	OnGiveUp func(err error, attempts int, elapsed time.Duration)
+
+	// OnSuccess is called when an attempt succeeds.
+	OnSuccess func(attempts int, elapsed time.Duration)
+}
+
+func (h *Hooks) attempt(attempt int) {
+	if h != nil && h.OnAttempt != nil {
+		h.OnAttempt(attempt)
+	}
+}
+
+func (h *Hooks) retry(attempt int, err error, delay time.Duration) {
+	if h != nil && h.OnRetry != nil {
+		h.OnRetry(attempt, err, delay)
+	}
+}
+
+func (h *Hooks) giveUp(err error, attempts int, elapsed time.Duration) {
+	if h != nil && h.OnGiveUp != nil {
+		h.OnGiveUp(err, attempts, elapsed)
+	}
+}
+
+func (h *Hooks) success(attempts int, elapsed time.Duration) {
+	if h != nil && h.OnSuccess != nil {
+		h.OnSuccess(attempts, elapsed)
+	}
+}
@@ -0,0 +1,83 @@
+package errguard
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestHooks(t *testing.T) {
+	var events []string
+	hooks := &Hooks{
+		OnAttempt: func(attempt int) {
+			events = append(events, fmt.Sprintf("attempt %d", attempt))
+		},
+		OnRetry: func(attempt int, err error, delay time.Duration) {
+			events = append(events, fmt.Sprintf("retry %d %v %v", attempt, err, delay))
+		},
+		OnGiveUp: func(err error, attempts int, elapsed time.Duration) {
+			events = append(events, fmt.Sprintf("give up %d %v", attempts, err))
+		},
+		OnSuccess: func(attempts int, elapsed time.Duration) {
+			events = append(events, fmt.Sprintf("success %d", attempts))
+		},
+	}
+	guard := Guard{
+		Backoff:     ConstantBackoff{Interval: time.Millisecond},
+		MaxAttempts: 2,
+		Hooks:       hooks,
+	}
+
+	var attempt int
+	guard.Run(context.Background(), func() error {
+		attempt++
+		if attempt < 2 {
+			return Retry(errors.New("test error"))
+		}
+		return nil
+	})
+	guard.Run(context.Background(), func() error {
+		return Retry(errors.New("test error"))
+	})
+	guard.Run(context.Background(), func() error {
+		return errors.New("fatal error")
+	})
+
+	want := []string{
+		"attempt 1",
+		"retry 1 test error 1ms",
+		"attempt 2",
+		"success 2",
+		"attempt 1",
+		"retry 1 test error 1ms",
+		"attempt 2",
+		"give up 2 errguard: maximum attempts reached after 2 attempts: test error",
+		"attempt 1",
+		"give up 1 fatal error",
+	}
+	if !reflect.DeepEqual(events, want) {
+		t.Errorf("got=%q\nwant=%q", events, want)
+	}
+}
+
+func TestDefaultHooks(t *testing.T) {
+	defer func(hooks *Hooks) { DefaultHooks = hooks }(DefaultHooks)
+	var successes int
+	DefaultHooks = &Hooks{
+		OnSuccess: func(attempts int, elapsed time.Duration) {
+			successes++
+		},
+	}
+
+	// partially filled hooks do not panic
+	(&Guard{Hooks: &Hooks{}}).Run(context.Background(), func() error { return nil })
+
+	var guard Guard
+	guard.Run(context.Background(), func() error { return nil })
+	if got, want := successes, 1; got != want {
+		t.Errorf("got=%v, want=%v", got, want)
+	}
+}
@@ -0,0 +1,86 @@
+package errguard
+
+import (
+	"math/rand"
+	"time"
+)
+
+// Jitter determines how a guard randomizes the delay computed by its
+// backoff. Randomizing the delay prevents many callers that encounter
+// the same error condition at the same time from retrying in lock step.
+type Jitter int
+
+// Jitter modes.
+const (
+	// NoJitter waits for exactly the delay computed by the backoff.
+	NoJitter Jitter = iota
+
+	// FullJitter waits for a random duration between zero and the
+	// delay computed by the backoff.
+	FullJitter
+
+	// EqualJitter waits for half the delay computed by the backoff,
+	// plus a random duration between zero and the other half.
+	EqualJitter
+
+	// DecorrelatedJitter waits for a random duration between the
+	// backoff's initial delay and three times the previous delay,
+	// limited to the delay computed by the backoff.
+	DecorrelatedJitter
+)
+
+// Rand is the source of random numbers used to apply jitter.
+// A *rand.Rand satisfies this interface, but note that it is not
+// safe for concurrent use by multiple goroutines.
+type Rand interface {
+	// Int63n returns a non-negative pseudo-random number in [0,n).
+	Int63n(n int64) int64
+}
+
+// delay returns the duration to wait after the attempt, given the
+// previous duration waited (zero for the first retry).
+func (j Jitter) delay(r Rand, backoff Backoff, attempt int, prev time.Duration) time.Duration {
+	delay := backoff.Delay(attempt)
+	switch j {
+	case FullJitter:
+		return randDuration(r, 0, delay)
+	case EqualJitter:
+		half := delay / 2
+		return half + randDuration(r, 0, delay-half)
+	case DecorrelatedJitter:
+		initial := backoff.Delay(1)
+		if prev <= 0 {
+			prev = initial
+		}
+		upper := prev * 3
+		if upper < prev || upper > delay {
+			// overflow or exceeds the backoff's delay
+			upper = delay
+		}
+		if initial > upper {
+			initial = upper
+		}
+		return randDuration(r, initial, upper)
+	}
+	return delay
+}
+
+// randDuration returns a random duration in [min,max].
+func randDuration(r Rand, min, max time.Duration) time.Duration {
+	n := int64(max - min)
+	if n <= 0 {
+		return min
+	}
+	if n+1 > 0 {
+		n++
+	}
+	return min + time.Duration(r.Int63n(n))
+}
+
+// globalRand uses the top-level functions of math/rand,
+// which are safe for concurrent use.
+type globalRand struct{}
+
+func (globalRand) Int63n(n int64) int64 {
+	return rand.Int63n(n)
+}
@@ -0,0 +1,96 @@
+package errguard
+
+import (
+	"context"
+	"math/rand"
+	"testing"
+	"time"
+
+	"github.com/jjeffery/errors"
+)
+
+// fixedRand returns a fixed fraction of n.
+type fixedRand float64
+
+func (r fixedRand) Int63n(n int64) int64 {
+	v := int64(float64(n) * float64(r))
+	if v >= n {
+		v = n - 1
+	}
+	return v
+}
+
+func TestJitter(t *testing.T) {
+	ms := time.Millisecond
+	tests := []struct {
+		jitter  Jitter
+		rand    Rand
+		delay   time.Duration
+		initial time.Duration
+		prev    time.Duration
+		want    time.Duration
+	}{
+		{NoJitter, fixedRand(0), 100 * ms, 10 * ms, 0, 100 * ms},
+		{FullJitter, fixedRand(0), 100 * ms, 10 * ms, 0, 0},
+		{FullJitter, fixedRand(0.5), 100 * ms, 10 * ms, 0, 50 * ms},
+		{EqualJitter, fixedRand(0), 100 * ms, 10 * ms, 0, 50 * ms},
+		{EqualJitter, fixedRand(1), 100 * ms, 10 * ms, 0, 100 * ms},
+		{DecorrelatedJitter, fixedRand(0), 100 * ms, 10 * ms, 20 * ms, 10 * ms},
+		{DecorrelatedJitter, fixedRand(1), 100 * ms, 10 * ms, 20 * ms, 60 * ms},
+		{DecorrelatedJitter, fixedRand(1), 100 * ms, 10 * ms, 0, 30 * ms},
+		{DecorrelatedJitter, fixedRand(1), 100 * ms, 10 * ms, 50 * ms, 100 * ms},
+		{FullJitter, fixedRand(0.5), 0, 0, 0, 0},
+	}
+
+	for i, tt := range tests {
+		backoff := backoffFunc(func(attempt int) time.Duration {
+			if attempt == 1 {
+				return tt.initial
+			}
+			return tt.delay
+		})
+		if got := tt.jitter.delay(tt.rand, backoff, 2, tt.prev); got != tt.want {
+			t.Errorf("%d: got=%v, want=%v", i, got, tt.want)
+		}
+	}
+}
+
+func TestJitterBounds(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	backoff := ExponentialBackoff{Initial: 10 * time.Millisecond, Max: 100 * time.Millisecond}
+	delay := backoff.Delay(5)
+	initial := backoff.Delay(1)
+	for i := 0; i < 1000; i++ {
+		if got := FullJitter.delay(r, backoff, 5, 0); got < 0 || got > delay {
+			t.Fatalf("full: got=%v, want in [0,%v]", got, delay)
+		}
+		if got := EqualJitter.delay(r, backoff, 5, 0); got < delay/2 || got > delay {
+			t.Fatalf("equal: got=%v, want in [%v,%v]", got, delay/2, delay)
+		}
+		if got := DecorrelatedJitter.delay(r, backoff, 5, 20*time.Millisecond); got < initial || got > delay {
+			t.Fatalf("decorrelated: got=%v, want in [%v,%v]", got, initial, delay)
+		}
+	}
+}
+
+func TestGuardJitter(t *testing.T) {
+	guard := Guard{
+		Backoff: ConstantBackoff{Interval: time.Hour},
+		Jitter:  FullJitter,
+		Rand:    fixedRand(0),
+	}
+	var attempt int
+	err := guard.Run(context.Background(), func() error {
+		attempt++
+		if attempt < 3 {
+			return Retry(errors.New("test error"))
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("got=%v, want nil", err)
+	}
+	if got, want := attempt, 3; got != want {
+		t.Errorf("got=%v, want=%v", got, want)
+	}
+}
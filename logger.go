@@ -14,3 +14,47 @@ type noopLogger struct{}
 func (logger noopLogger) Log(v ...interface{}) error {
 	return nil
 }
+
+// LogLevels determines the levels of the messages logged by a guard.
+// Levels are logged as the value of the "level" key, and are one of
+// "debug", "info", "warn" or "error".
+type LogLevels struct {
+	// WarnAfter is the number of failed attempts that are logged
+	// at "info" level before subsequent failed attempts are logged
+	// at "warn" level. If zero, 1 is used, so only the first
+	// failed attempt is logged at "info" level.
+	WarnAfter int
+
+	// GiveUp is the level used when the guard gives up.
+	// If blank, "error" is used.
+	GiveUp string
+
+	// Success is the level used when an attempt succeeds after
+	// retrying. If blank, "info" is used.
+	Success string
+}
+
+func (l LogLevels) retry(attempt int) string {
+	warnAfter := l.WarnAfter
+	if warnAfter <= 0 {
+		warnAfter = 1
+	}
+	if attempt <= warnAfter {
+		return "info"
+	}
+	return "warn"
+}
+
+func (l LogLevels) giveUp() string {
+	if l.GiveUp == "" {
+		return "error"
+	}
+	return l.GiveUp
+}
+
+func (l LogLevels) success() string {
+	if l.Success == "" {
+		return "info"
+	}
+	return l.Success
+}
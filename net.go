@@ -0,0 +1,45 @@
+package errguard
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"syscall"
+)
+
+// IsTransientNetworkError reports whether err indicates a transient
+// network failure. It recognises, anywhere in the error chain:
+//
+//   - a net.Error that reports a timeout;
+//   - syscall.ECONNRESET, syscall.ECONNREFUSED and syscall.EPIPE;
+//   - a *net.DNSError that is temporary or a timeout.
+//
+// If idempotent is true, io.ErrUnexpectedEOF is also treated as
+// transient. A connection closed part way through a response might
+// mean the request was processed, so this is only suitable for
+// operations that are safe to repeat.
+//
+// Errors caused by the cancellation or deadline of a context are
+// not transient, even though context.DeadlineExceeded reports itself
+// as a timeout.
+func IsTransientNetworkError(err error, idempotent bool) bool {
+	if err == nil || errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+
+	var dnsErr *net.DNSError
+	if errors.As(err, &dnsErr) {
+		return dnsErr.IsTemporary || dnsErr.IsTimeout
+	}
+
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return true
+	}
+
+	return errors.Is(err, syscall.ECONNRESET) ||
+		errors.Is(err, syscall.ECONNREFUSED) ||
+		errors.Is(err, syscall.EPIPE) ||
+		(idempotent && errors.Is(err, io.ErrUnexpectedEOF))
+}
@@ -0,0 +1,109 @@
+package errguard
+
+import (
+	"fmt"
+
+	"github.com/go-stack/stack"
+	"github.com/jjeffery/kv"
+)
+
+// PanicPolicy determines what a guard does when the function
+// it is running panics.
+type PanicPolicy int
+
+// Panic policies.
+const (
+	// PanicPropagate does not recover panics. This is the default.
+	PanicPropagate PanicPolicy = iota
+
+	// PanicReturn recovers panics and returns a *PanicError,
+	// which is not retried, even if the guard's ShouldRetry or
+	// Classifier would retry it.
+	PanicReturn
+
+	// PanicRetry recovers panics into a *PanicError, which is
+	// retried, even if the guard's ShouldRetry or Classifier
+	// would not retry it. If the guard gives up, the error it
+	// returns wraps the *PanicError.
+	PanicRetry
+
+	// PanicRethrow recovers panics, logs them at "error" level
+	// with their stack trace, and then panics again with the
+	// same value.
+	PanicRethrow
+)
+
+// PanicError is the error for a function that panicked while
+// being run by a guard with a panic policy of PanicReturn or
+// PanicRetry.
+type PanicError struct {
+	// Value is the value passed to panic.
+	Value interface{}
+
+	// Stack is the stack trace of the panic.
+	Stack stack.CallStack
+
+	retry bool
+}
+
+func (e *PanicError) Error() string {
+	return fmt.Sprintf("panic: %v", e.Value)
+}
+
+// Unwrap returns the panic value if it is an error.
+func (e *PanicError) Unwrap() error {
+	err, _ := e.Value.(error)
+	return err
+}
+
+// ShouldRetry returns true if the panic policy is PanicRetry.
+func (e *PanicError) ShouldRetry() bool {
+	return e.retry
+}
+
+// recoverPanics returns a function that calls f and handles
+// any panic according to the guard's panic policy.
+func (g *Guard) recoverPanics(logger Logger, f func() error) func() error {
+	if g.PanicPolicy == PanicPropagate {
+		return f
+	}
+	return func() (err error) {
+		defer func() {
+			r := recover()
+			if r == nil {
+				return
+			}
+			panicErr := &PanicError{
+				Value: r,
+				Stack: panicStack(),
+				retry: g.PanicPolicy == PanicRetry,
+			}
+			if g.PanicPolicy == PanicRethrow {
+				keyvals := []interface{}{
+					kv.P("level", "error"),
+					kv.P("msg", "recovered panic"),
+					panicErr,
+					kv.P("stack", panicErr.Stack),
+				}
+				logger.Log(kv.Flatten(keyvals)...)
+				panic(r)
+			}
+			err = panicErr
+		}()
+		return f()
+	}
+}
+
+// panicStack returns the stack trace of a panic, starting
+// at the function that called panic. It must be called
+// from the deferred function that recovers the panic.
+func panicStack() stack.CallStack {
+	trace := stack.Trace()
+	for i, call := range trace {
+		if call.Frame().Function == "runtime.gopanic" {
+			trace = trace[i+1:]
+			break
+		}
+	}
+	return trace.TrimRuntime()
+}
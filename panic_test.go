@@ -0,0 +1,140 @@
+package errguard
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jjeffery/errguard/errguardtest"
+)
+
+func TestPanicPolicy(t *testing.T) {
+	panicErr := errors.New("driver: bad connection")
+	newGuard := func(policy PanicPolicy, logger Logger) *Guard {
+		return &Guard{
+			Clock:       errguardtest.NewAutoClock(time.Now()),
+			MaxAttempts: 3,
+			PanicPolicy: policy,
+			Logger:      logger,
+		}
+	}
+
+	t.Run("return", func(t *testing.T) {
+		var attempts int
+		err := newGuard(PanicReturn, nil).Run(context.Background(), func() error {
+			attempts++
+			panic(panicErr)
+		})
+		var p *PanicError
+		if !errors.As(err, &p) {
+			t.Fatalf("got=%T, want *PanicError", err)
+		}
+		if got, want := attempts, 1; got != want {
+			t.Errorf("attempts: got=%v, want=%v", got, want)
+		}
+		if !errors.Is(err, panicErr) {
+			t.Errorf("want errors.Is(err, panicErr)")
+		}
+		if got, want := err.Error(), "panic: driver: bad connection"; got != want {
+			t.Errorf("got=%q, want=%q", got, want)
+		}
+		if len(p.Stack) == 0 || !strings.Contains(fmt.Sprint(p.Stack[0]), "panic_test.go") {
+			t.Errorf("stack: got=%v, want to start in panic_test.go", p.Stack)
+		}
+	})
+
+	t.Run("retry", func(t *testing.T) {
+		var attempts int
+		err := newGuard(PanicRetry, nil).Run(context.Background(), func() error {
+			attempts++
+			if attempts < 3 {
+				panic("transient")
+			}
+			return nil
+		})
+		if err != nil {
+			t.Fatalf("got=%v, want nil", err)
+		}
+		if got, want := attempts, 3; got != want {
+			t.Errorf("attempts: got=%v, want=%v", got, want)
+		}
+
+		err = newGuard(PanicRetry, nil).Run(context.Background(), func() error {
+			panic("transient")
+		})
+		var p *PanicError
+		if !errors.Is(err, ErrMaxAttempts) || !errors.As(err, &p) {
+			t.Errorf("got=%v, want *PanicError and %v", err, ErrMaxAttempts)
+		}
+	})
+
+	t.Run("custom ShouldRetry", func(t *testing.T) {
+		guard := newGuard(PanicRetry, nil)
+		guard.ShouldRetry = func(err error) bool {
+			return errors.Is(err, context.DeadlineExceeded)
+		}
+		var attempts int
+		err := guard.Run(context.Background(), func() error {
+			attempts++
+			if attempts < 3 {
+				panic("transient")
+			}
+			return nil
+		})
+		if err != nil {
+			t.Fatalf("got=%v, want nil", err)
+		}
+		if got, want := attempts, 3; got != want {
+			t.Errorf("attempts: got=%v, want=%v", got, want)
+		}
+	})
+
+	t.Run("custom Classifier", func(t *testing.T) {
+		guard := newGuard(PanicReturn, nil)
+		guard.Classifier = ClassifierFunc(func(err error) Decision {
+			return DecideRetry()
+		})
+		var attempts int
+		err := guard.Run(context.Background(), func() error {
+			attempts++
+			panic(panicErr)
+		})
+		var p *PanicError
+		if !errors.As(err, &p) || errors.Is(err, ErrMaxAttempts) {
+			t.Errorf("got=%v, want *PanicError", err)
+		}
+		if got, want := attempts, 1; got != want {
+			t.Errorf("attempts: got=%v, want=%v", got, want)
+		}
+	})
+
+	t.Run("rethrow", func(t *testing.T) {
+		logger := &errguardtest.Logger{}
+		defer func() {
+			if got, want := recover(), interface{}(panicErr); got != want {
+				t.Errorf("recover: got=%v, want=%v", got, want)
+			}
+			logger.AssertLevels(t, "error")
+			if entries := logger.Entries(); len(entries) > 0 && entries[0].Value("stack") == nil {
+				t.Errorf("want stack to be logged")
+			}
+		}()
+		newGuard(PanicRethrow, logger).Run(context.Background(), func() error {
+			panic(panicErr)
+		})
+	})
+
+	t.Run("propagate", func(t *testing.T) {
+		defer func() {
+			if got := recover(); got != "boom" {
+				t.Errorf("recover: got=%v, want boom", got)
+			}
+		}()
+		newGuard(PanicPropagate, nil).Run(context.Background(), func() error {
+			panic("boom")
+		})
+	})
+}
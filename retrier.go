@@ -0,0 +1,387 @@
+package errguard
+
+import (
+	"context"
+	"iter"
+	"time"
+
+	"github.com/go-stack/stack"
+	"github.com/jjeffery/kv"
+)
+
+// Retrier runs a retry loop for code that cannot easily be
+// wrapped in a function, with the same backoff, limits, logging,
+// hooks and errors as Run. It is used like this:
+//
+//	r := guard.Start(ctx)
+//	for r.Next() {
+//		err := doSomething()
+//		if !r.Retry(err) {
+//			break
+//		}
+//	}
+//	return r.Err()
+//
+// The guard's PanicPolicy does not apply to a Retrier. A Retrier is
+// not safe for concurrent use by multiple goroutines.
+type Retrier struct {
+	g          *Guard
+	ctx        context.Context
+	classifier Classifier
+	logger     Logger
+	backoff    Backoff
+	random     Rand
+	clock      Clock
+	hooks      *Hooks
+
+	// skip is the number of frames between the code running the
+	// loop and the calls to Next and Retry, so that the logged
+	// caller is the code running the loop.
+	skip int
+
+	start        time.Time
+	attempt      int
+	attemptStart time.Time
+	delay        time.Duration
+	last         error
+	history      []AttemptRecord
+	classes      []classCount
+	generation   uint64 // breaker generation of the current attempt
+	pending      bool
+	done         bool
+	err          error
+
+	// iterating is true for a Retrier yielded by Attempts
+	iterating bool
+}
+
+// Start returns a Retrier for a retry loop using the guard's
+// settings. See Retrier for an example.
+func (g *Guard) Start(ctx context.Context) *Retrier {
+	return g.start(ctx, 0)
+}
+
+// Attempts returns an iterator over the attempts of a retry loop,
+// for use with a range statement. Before each attempt it yields the
+// Retrier and a nil error. The body reports the attempt's result by
+// calling Retry. If the guard gives up, or if the attempt fails with
+// an error that should not be retried, the iterator yields the error
+// that Run would have returned, which the body should return:
+//
+//	for r, err := range guard.Attempts(ctx) {
+//		if err != nil {
+//			return err
+//		}
+//		r.Retry(doSomething())
+//	}
+//	return nil
+//
+// Within the loop, Retry always returns true, so that the loop
+// continues and the iterator can yield the final error. If the body
+// continues to the next iteration without calling Retry, the iterator
+// yields ErrNotReported.
+//
+// Leaving the loop with break or return before calling Retry stops
+// retrying, and counts as a success for the guard's Breaker, in the
+// same way as an error that should not be retried. An attempt that
+// panics counts as a failure.
+func (g *Guard) Attempts(ctx context.Context) iter.Seq2[*Retrier, error] {
+	return func(yield func(*Retrier, error) bool) {
+		r := g.start(ctx, 0)
+		r.iterating = true
+		defer r.abandon()
+		for r.next(2) {
+			if !yield(r, nil) {
+				r.leave()
+				return
+			}
+		}
+		if err := r.Err(); err != nil {
+			yield(r, err)
+		}
+	}
+}
+
+func (g *Guard) start(ctx context.Context, skip int) *Retrier {
+	r := &Retrier{
+		g:          g,
+		ctx:        ctx,
+		classifier: g.Classifier,
+		logger:     g.Logger,
+		backoff:    g.Backoff,
+		random:     g.Rand,
+		clock:      g.Clock,
+		hooks:      g.Hooks,
+		skip:       skip,
+	}
+	if r.classifier == nil {
+		shouldRetry := g.ShouldRetry
+		if shouldRetry == nil {
+			shouldRetry = ShouldRetry
+		}
+		r.classifier = ShouldRetryFunc(shouldRetry)
+	}
+	if r.logger == nil {
+		r.logger = DefaultLogger
+	}
+	if r.backoff == nil {
+		r.backoff = DefaultBackoff
+	}
+	if r.random == nil {
+		r.random = globalRand{}
+	}
+	if r.clock == nil {
+		r.clock = systemClock{}
+	}
+	if r.hooks == nil {
+		r.hooks = DefaultHooks
+	}
+	if g.Budget != nil {
+		g.Budget.deposit()
+	}
+	r.start = r.clock.Now()
+	return r
+}
+
+// Next prepares for the next attempt and reports whether it should
+// be made. It returns true for the first attempt, unless the guard's
+// Breaker is open, and afterwards only if Retry returned true.
+//
+// Retry must be called to report the result of each attempt. If Next
+// is called again without calling Retry, it returns false and Err
+// returns ErrNotReported. If the loop is left without calling Retry,
+// the result is not recorded, and a half-open Breaker does not allow
+// another trial attempt until its Cooldown has passed.
+func (r *Retrier) Next() bool {
+	return r.next(2 + r.skip)
+}
+
+// next implements Next. The caller logged if the guard gives up is
+// depth frames above next.
+func (r *Retrier) next(depth int) bool {
+	if r.done {
+		return false
+	}
+	if r.pending {
+		// the result of the previous attempt was not reported
+		r.abandon()
+		r.finish(ErrNotReported)
+		return false
+	}
+	if r.g.Breaker != nil {
+		generation, ok := r.g.Breaker.allow(r.logger, r.clock)
+		if !ok {
+			r.giveUp(stack.Caller(depth), ErrBreakerOpen)
+			return false
+		}
+		r.generation = generation
+	}
+	r.attempt++
+	r.pending = true
+	r.attemptStart = r.clock.Now()
+	r.hooks.attempt(r.attempt)
+	return true
+}
+
+// Retry reports the error returned by the current attempt, which is
+// nil if the attempt succeeded. If the error should be retried, Retry
+// waits before the next attempt and returns true. Otherwise it returns
+// false, and Err returns the error that Run would have returned.
+// For a Retrier yielded by Attempts, Retry always returns true.
+func (r *Retrier) Retry(err error) bool {
+	return r.retry(err) || r.iterating
+}
+
+// retry implements Retry.
+func (r *Retrier) retry(err error) bool {
+	if !r.pending {
+		return false
+	}
+	r.pending = false
+	r.last = err
+	g := r.g
+
+	if err == nil {
+		if g.Breaker != nil {
+			g.Breaker.record(r.logger, r.clock, r.generation, false)
+		}
+		if r.attempt > 1 {
+			keyvals := []interface{}{
+				kv.P("level", g.LogLevels.success()),
+				kv.P("msg", "succeeded after retrying"),
+				kv.P("caller", stack.Caller(2+r.skip)),
+				kv.P("attempts", r.attempt),
+				kv.P("elapsed", r.clock.Now().Sub(r.start)),
+			}
+			r.logger.Log(kv.Flatten(keyvals)...)
+		}
+		r.finish(nil)
+		return false
+	}
+
+	caller := stack.Caller(2 + r.skip)
+	r.history = appendHistory(r.history, AttemptRecord{
+		Number: r.attempt,
+		Err:    err,
+		Start:  r.attemptStart,
+	})
+	var decision Decision
+	if panicErr, ok := findInChain[*PanicError](err); ok {
+		// the panic policy applies, whatever the classifier
+		if panicErr.retry {
+			decision = DecideRetry()
+		}
+	} else if !IsPermanent(err) {
+		decision = r.classifier.Classify(err)
+	}
+	if g.Breaker != nil {
+		g.Breaker.record(r.logger, r.clock, r.generation, decision.Retry)
+	}
+	if !decision.Retry {
+		if decision.Err != nil {
+			err = decision.Err
+		}
+		r.finish(err)
+		return false
+	}
+	classAttempts := r.countClass(decision.Class)
+	if (g.MaxAttempts > 0 && r.attempt >= g.MaxAttempts) ||
+		(decision.MaxAttempts > 0 && classAttempts >= decision.MaxAttempts) {
+		r.giveUp(caller, ErrMaxAttempts)
+		return false
+	}
+	if decision.Delay > 0 {
+		r.delay = decision.Delay
+	} else if retryDelay, ok := findInChain[retryDelayer](err); ok && retryDelay.RetryDelay() > 0 {
+		r.delay = retryDelay.RetryDelay()
+	} else {
+		r.delay = g.Jitter.delay(r.random, r.backoff, r.attempt, r.delay)
+	}
+	if g.MaxElapsed > 0 && r.clock.Now().Sub(r.start)+r.delay > g.MaxElapsed {
+		r.giveUp(caller, ErrMaxElapsed)
+		return false
+	}
+	if deadline, ok := r.ctx.Deadline(); ok && r.clock.Now().Add(r.delay).After(deadline) {
+		// no point waiting if the context will be done before the next attempt
+		r.giveUp(caller, ErrContextDeadline)
+		return false
+	}
+	if g.Budget != nil && !g.Budget.withdraw() {
+		r.giveUp(caller, ErrBudgetExhausted)
+		return false
+	}
+
+	// At this point an optimistic locking exception has occurred
+	// and there is still time. Log a message, wait and retry.
+	r.history[len(r.history)-1].Delay = r.delay
+	keyvals := []interface{}{
+		kv.P("level", g.LogLevels.retry(r.attempt)),
+		kv.P("msg", "retrying"),
+		err,
+		kv.P("caller", caller),
+		kv.P("attempt", r.attempt),
+		kv.P("delay", r.delay),
+	}
+	r.logger.Log(kv.Flatten(keyvals)...)
+	r.hooks.retry(r.attempt, err, r.delay)
+
+	select {
+	case <-r.ctx.Done():
+		r.giveUp(caller, contextReason(r.ctx))
+		return false
+	case <-r.clock.After(r.delay):
+	}
+	return true
+}
+
+// Err returns the error from the retry loop, which is nil if the
+// last attempt succeeded. If the guard gave up, the error is a
+// *RetryError, as described for Run.
+func (r *Retrier) Err() error {
+	return r.err
+}
+
+// Attempt returns the number of the current attempt, starting at 1.
+func (r *Retrier) Attempt() int {
+	return r.attempt
+}
+
+// abandon ends the loop if the result of the current attempt has
+// not been reported, such as when the attempt panics. The attempt
+// is recorded as a failure, so that a half-open Breaker does not
+// wait for the result of its trial attempt.
+func (r *Retrier) abandon() {
+	if !r.pending {
+		return
+	}
+	r.pending = false
+	r.done = true
+	if r.g.Breaker != nil {
+		r.g.Breaker.record(r.logger, r.clock, r.generation, true)
+	}
+}
+
+// leave ends the loop if the result of the current attempt has
+// not been reported because the loop was left, which stops retrying
+// in the same way as an error that should not be retried.
+func (r *Retrier) leave() {
+	if !r.pending {
+		return
+	}
+	r.pending = false
+	r.done = true
+	if r.g.Breaker != nil {
+		r.g.Breaker.record(r.logger, r.clock, r.generation, false)
+	}
+}
+
+// classCount is the number of failed attempts for a class of error.
+type classCount struct {
+	class string
+	count int
+}
+
+// countClass counts a failed attempt for the class of error, and
+// returns the number of failed attempts for that class.
+func (r *Retrier) countClass(class string) int {
+	for i := range r.classes {
+		if r.classes[i].class == class {
+			r.classes[i].count++
+			return r.classes[i].count
+		}
+	}
+	r.classes = append(r.classes, classCount{class: class, count: 1})
+	return 1
+}
+
+// giveUp finishes the loop with a *RetryError and logs it.
+func (r *Retrier) giveUp(caller stack.Call, reason error) {
+	retryErr := &RetryError{
+		Reason:   reason,
+		Last:     r.last,
+		Attempts: r.attempt,
+		Elapsed:  r.clock.Now().Sub(r.start),
+		History:  r.history,
+	}
+	keyvals := []interface{}{
+		kv.P("level", r.g.LogLevels.giveUp()),
+		kv.P("msg", "giving up"),
+		retryErr,
+		kv.P("caller", caller),
+		kv.P("attempts", r.attempt),
+		kv.P("elapsed", retryErr.Elapsed),
+	}
+	r.logger.Log(kv.Flatten(keyvals)...)
+	r.finish(retryErr)
+}
+
+// finish ends the loop and calls the hooks.
+func (r *Retrier) finish(err error) {
+	r.done = true
+	r.err = err
+	if err != nil {
+		r.hooks.giveUp(err, r.attempt, r.clock.Now().Sub(r.start))
+	} else {
+		r.hooks.success(r.attempt, r.clock.Now().Sub(r.start))
+	}
+}
@@ -0,0 +1,269 @@
+package errguard
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jjeffery/errguard/errguardtest"
+)
+
+func TestRetrier(t *testing.T) {
+	logger := &errguardtest.Logger{}
+	var succeeded int
+	guard := &Guard{
+		Clock:  errguardtest.NewAutoClock(time.Now()),
+		Logger: logger,
+		Hooks: &Hooks{
+			OnSuccess: func(attempts int, elapsed time.Duration) {
+				succeeded = attempts
+			},
+		},
+	}
+
+	var attempts []int
+	r := guard.Start(context.Background())
+	for r.Next() {
+		attempts = append(attempts, r.Attempt())
+		var err error
+		if r.Attempt() < 3 {
+			err = Retry(errors.New("test error"))
+		}
+		if !r.Retry(err) {
+			break
+		}
+	}
+	if err := r.Err(); err != nil {
+		t.Fatalf("got=%v, want nil", err)
+	}
+	if got, want := fmt.Sprint(attempts), "[1 2 3]"; got != want {
+		t.Errorf("attempts: got=%v, want=%v", got, want)
+	}
+	if got, want := succeeded, 3; got != want {
+		t.Errorf("hook: got=%v, want=%v", got, want)
+	}
+	logger.AssertLevels(t, "info", "warn", "info")
+	for _, e := range logger.Entries() {
+		if caller := e.Value("caller"); !strings.HasPrefix(fmt.Sprint(caller), "retrier_test.go:") {
+			t.Errorf("caller: got=%v, want retrier_test.go", caller)
+		}
+	}
+
+	// calls after the loop has finished have no effect
+	if r.Next() || r.Retry(errors.New("test error")) {
+		t.Error("want false after loop finished")
+	}
+}
+
+func TestRetrierGiveUp(t *testing.T) {
+	testErr := errors.New("test error")
+	guard := &Guard{
+		Clock:       errguardtest.NewAutoClock(time.Now()),
+		MaxAttempts: 2,
+	}
+
+	r := guard.Start(context.Background())
+	for r.Next() {
+		if !r.Retry(Retry(testErr)) {
+			break
+		}
+	}
+	var retryErr *RetryError
+	if !errors.As(r.Err(), &retryErr) {
+		t.Fatalf("got=%T, want *RetryError", r.Err())
+	}
+	if !errors.Is(retryErr, ErrMaxAttempts) || !errors.Is(retryErr, testErr) {
+		t.Errorf("got=%v, want max attempts and test error", retryErr)
+	}
+	if got, want := retryErr.Attempts, 2; got != want {
+		t.Errorf("attempts: got=%v, want=%v", got, want)
+	}
+
+	// not retryable
+	r = guard.Start(context.Background())
+	for r.Next() {
+		if !r.Retry(testErr) {
+			break
+		}
+	}
+	if got, want := r.Err(), testErr; got != want {
+		t.Errorf("got=%v, want=%v", got, want)
+	}
+
+	// result not reported
+	r = guard.Start(context.Background())
+	for r.Next() {
+	}
+	if got, want := r.Err(), ErrNotReported; got != want {
+		t.Errorf("got=%v, want=%v", got, want)
+	}
+	if got, want := r.Attempt(), 1; got != want {
+		t.Errorf("attempts: got=%v, want=%v", got, want)
+	}
+}
+
+func TestAttempts(t *testing.T) {
+	logger := &errguardtest.Logger{}
+	guard := &Guard{
+		Clock:       errguardtest.NewAutoClock(time.Now()),
+		MaxAttempts: 3,
+		Logger:      logger,
+	}
+
+	run := func(errs ...error) ([]int, error) {
+		var attempts []int
+		for r, err := range guard.Attempts(context.Background()) {
+			if err != nil {
+				return attempts, err
+			}
+			attempts = append(attempts, r.Attempt())
+			r.Retry(errs[r.Attempt()-1])
+		}
+		return attempts, nil
+	}
+
+	testErr := errors.New("test error")
+	attempts, err := run(Retry(testErr), nil)
+	if err != nil {
+		t.Fatalf("got=%v, want nil", err)
+	}
+	if got, want := fmt.Sprint(attempts), "[1 2]"; got != want {
+		t.Errorf("attempts: got=%v, want=%v", got, want)
+	}
+
+	attempts, err = run(Retry(testErr), Retry(testErr), Retry(testErr))
+	if !errors.Is(err, ErrMaxAttempts) {
+		t.Errorf("got=%v, want=%v", err, ErrMaxAttempts)
+	}
+	if got, want := fmt.Sprint(attempts), "[1 2 3]"; got != want {
+		t.Errorf("attempts: got=%v, want=%v", got, want)
+	}
+	for _, e := range logger.Entries() {
+		if caller := e.Value("caller"); !strings.HasPrefix(fmt.Sprint(caller), "retrier_test.go:") {
+			t.Errorf("caller: got=%v, want retrier_test.go", caller)
+		}
+	}
+
+	// not retryable
+	attempts, err = run(Retry(testErr), testErr)
+	if got, want := err, testErr; got != want {
+		t.Errorf("got=%v, want=%v", got, want)
+	}
+	if got, want := fmt.Sprint(attempts), "[1 2]"; got != want {
+		t.Errorf("attempts: got=%v, want=%v", got, want)
+	}
+
+	// breaking when Retry returns false still yields the final error
+	var calls int
+	err = func() error {
+		for r, err := range guard.Attempts(context.Background()) {
+			if err != nil {
+				return err
+			}
+			calls++
+			if !r.Retry(Retry(testErr)) {
+				break
+			}
+		}
+		return nil
+	}()
+	if !errors.Is(err, ErrMaxAttempts) {
+		t.Errorf("got=%v, want=%v", err, ErrMaxAttempts)
+	}
+	if got, want := calls, 3; got != want {
+		t.Errorf("calls: got=%v, want=%v", got, want)
+	}
+
+	// result not reported
+	calls = 0
+	err = func() error {
+		for _, err := range guard.Attempts(context.Background()) {
+			if err != nil {
+				return err
+			}
+			calls++
+		}
+		return nil
+	}()
+	if got, want := err, ErrNotReported; got != want {
+		t.Errorf("got=%v, want=%v", got, want)
+	}
+	if got, want := calls, 1; got != want {
+		t.Errorf("calls: got=%v, want=%v", got, want)
+	}
+}
+
+func TestAttemptsBreaker(t *testing.T) {
+	clock := errguardtest.NewAutoClock(time.Date(2017, 1, 2, 3, 4, 5, 0, time.UTC))
+	breaker := &Breaker{
+		ConsecutiveFailures: 1,
+		Cooldown:            time.Minute,
+	}
+	guard := &Guard{
+		Clock:       clock,
+		Breaker:     breaker,
+		MaxAttempts: 1,
+	}
+	trip := func() {
+		guard.Run(context.Background(), func() error {
+			return Retry(errors.New("test error"))
+		})
+		if got, want := breaker.State(), BreakerOpen; got != want {
+			t.Fatalf("state: got=%v, want=%v", got, want)
+		}
+	}
+
+	// open breaker ends the loop without an attempt
+	trip()
+	var calls int
+	err := func() error {
+		for _, err := range guard.Attempts(context.Background()) {
+			if err != nil {
+				return err
+			}
+			calls++
+		}
+		return nil
+	}()
+	if calls != 0 {
+		t.Errorf("calls: got=%v, want=0", calls)
+	}
+	if !errors.Is(err, ErrBreakerOpen) {
+		t.Errorf("got=%v, want=%v", err, ErrBreakerOpen)
+	}
+
+	// returning from the trial attempt without reporting
+	// the result counts as a success
+	clock.Advance(time.Minute)
+	func() {
+		for _, err := range guard.Attempts(context.Background()) {
+			if err != nil {
+				t.Fatal(err)
+			}
+			return
+		}
+	}()
+	if got, want := breaker.State(), BreakerClosed; got != want {
+		t.Errorf("state: got=%v, want=%v", got, want)
+	}
+
+	// a trial attempt that panics counts as a failure
+	trip()
+	clock.Advance(time.Minute)
+	func() {
+		defer func() {
+			if r := recover(); r == nil {
+				t.Fatal("want panic")
+			}
+		}()
+		for range guard.Attempts(context.Background()) {
+			panic("test panic")
+		}
+	}()
+	if got, want := breaker.State(), BreakerOpen; got != want {
+		t.Errorf("state: got=%v, want=%v", got, want)
+	}
+}
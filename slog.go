@@ -0,0 +1,65 @@
+package errguard
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/go-stack/stack"
+)
+
+// SlogLogger is a Logger that writes messages to a log/slog handler.
+// The "level" and "msg" values logged by the guard become the level
+// and message of the slog record, and the remaining key/value pairs
+// become its attributes. The caller becomes the record's source.
+type SlogLogger struct {
+	Handler slog.Handler
+}
+
+// NewSlogLogger returns a logger that writes messages to h.
+func NewSlogLogger(h slog.Handler) *SlogLogger {
+	return &SlogLogger{Handler: h}
+}
+
+// Log implements the Logger interface.
+func (l *SlogLogger) Log(v ...interface{}) error {
+	ctx := context.Background()
+	level, msg, keyvals := splitKeyvals(v)
+	slevel := slogLevel(level)
+	if !l.Handler.Enabled(ctx, slevel) {
+		return nil
+	}
+
+	var pc uintptr
+	var attrs []slog.Attr
+	for i := 0; i < len(keyvals); i += 2 {
+		key := keyvals[i].(string)
+		switch value := keyvals[i+1].(type) {
+		case stack.Call:
+			// slog expects a return address, as returned by
+			// runtime.Callers, rather than the PC of the call
+			pc = value.Frame().PC + 1
+			attrs = append(attrs, slog.Any(key, formatValue(value)))
+		case time.Duration:
+			attrs = append(attrs, slog.Duration(key, value))
+		default:
+			attrs = append(attrs, slog.Any(key, formatValue(value)))
+		}
+	}
+
+	record := slog.NewRecord(time.Now(), slevel, msg, pc)
+	record.AddAttrs(attrs...)
+	return l.Handler.Handle(ctx, record)
+}
+
+func slogLevel(level string) slog.Level {
+	switch level {
+	case "debug":
+		return slog.LevelDebug
+	case "warn", "warning":
+		return slog.LevelWarn
+	case "error":
+		return slog.LevelError
+	}
+	return slog.LevelInfo
+}
@@ -0,0 +1,64 @@
+package errguard
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSlogLogger(t *testing.T) {
+	var buf bytes.Buffer
+	handler := slog.NewTextHandler(&buf, &slog.HandlerOptions{
+		AddSource: true,
+		ReplaceAttr: func(groups []string, a slog.Attr) slog.Attr {
+			if a.Key == slog.TimeKey && len(groups) == 0 {
+				return slog.Attr{}
+			}
+			return a
+		},
+	})
+	guard := Guard{
+		Backoff:     ConstantBackoff{Interval: time.Millisecond},
+		MaxAttempts: 3,
+		Logger:      NewSlogLogger(handler),
+	}
+	_, _, line, _ := runtime.Caller(0)
+	guard.Run(context.Background(), func() error {
+		return Retry(errors.New("test error"))
+	})
+	source := fmt.Sprintf("slog_test.go:%d msg=", line+1)
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if got, want := len(lines), 3; got != want {
+		t.Fatalf("got=%v, want=%v\n%s", got, want, buf.String())
+	}
+	wants := [][]string{
+		{"level=INFO", `msg=retrying`, "attempt=1", "delay=1ms", source},
+		{"level=WARN", `msg=retrying`, "attempt=2", source},
+		{"level=ERROR", `msg="giving up"`, "attempts=3", source},
+	}
+	for i, want := range wants {
+		for _, w := range want {
+			if !strings.Contains(lines[i], w) {
+				t.Errorf("%d: got=%q, want to contain %q", i, lines[i], w)
+			}
+		}
+	}
+}
+
+func TestSlogLoggerEnabled(t *testing.T) {
+	var buf bytes.Buffer
+	logger := NewSlogLogger(slog.NewTextHandler(&buf, &slog.HandlerOptions{Level: slog.LevelWarn}))
+	logger.Log("level", "info", "msg", "not logged")
+	logger.Log("level", "debug", "msg", "not logged")
+	logger.Log("level", "warn", "msg", "logged")
+	if got, want := strings.Count(buf.String(), "\n"), 1; got != want {
+		t.Errorf("got=%v, want=%v\n%s", got, want, buf.String())
+	}
+}
@@ -0,0 +1,99 @@
+package errguard
+
+import (
+	"reflect"
+)
+
+// SQLSTATE codes that indicate the transaction should be retried.
+var sqlStates = map[string]bool{
+	"40001": true, // serialization_failure
+	"40P01": true, // deadlock_detected (PostgreSQL)
+	"55P03": true, // lock_not_available (PostgreSQL)
+}
+
+// Error numbers that indicate the transaction should be retried.
+// MySQL and SQL Server use different numbering schemes, but the
+// numbers of interest do not conflict.
+var sqlNumbers = map[int64]bool{
+	1205: true, // MySQL: lock wait timeout; SQL Server: deadlock victim
+	1213: true, // MySQL: deadlock found
+}
+
+// SQLite primary result codes that indicate the operation should be retried.
+var sqliteCodes = map[int64]bool{
+	5: true, // SQLITE_BUSY
+	6: true, // SQLITE_LOCKED
+}
+
+// IsTransientSQLError reports whether any error in err's chain
+// indicates a deadlock, serialization failure or lock timeout
+// reported by a database. It recognises:
+//
+//   - PostgreSQL SQLSTATE 40001, 40P01 and 55P03, from a
+//     SQLState() string method or a string Code field
+//     (github.com/lib/pq, github.com/jackc/pgx);
+//   - MySQL error numbers 1213 and 1205, from a Number field
+//     (github.com/go-sql-driver/mysql);
+//   - SQL Server error number 1205, from a SQLErrorNumber() int32
+//     method or a Number field (github.com/microsoft/go-mssqldb);
+//   - SQLite SQLITE_BUSY and SQLITE_LOCKED, from integer Code and
+//     ExtendedCode fields (github.com/mattn/go-sqlite3).
+func IsTransientSQLError(err error) bool {
+	return WalkChain(err, isSQLRetryable)
+}
+
+func isSQLRetryable(err error) bool {
+	type sqlStater interface {
+		SQLState() string
+	}
+	type sqlErrorNumberer interface {
+		SQLErrorNumber() int32
+	}
+
+	if e, ok := err.(sqlStater); ok {
+		return sqlStates[e.SQLState()]
+	}
+	if e, ok := err.(sqlErrorNumberer); ok {
+		return sqlNumbers[int64(e.SQLErrorNumber())]
+	}
+
+	v := reflect.ValueOf(err)
+	for v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return false
+		}
+		v = v.Elem()
+	}
+	if v.Kind() != reflect.Struct {
+		return false
+	}
+
+	code := v.FieldByName("Code")
+	if code.IsValid() && code.Kind() == reflect.String {
+		return sqlStates[code.String()]
+	}
+	if number, ok := intField(v, "Number"); ok {
+		return sqlNumbers[number]
+	}
+	if code, ok := intField(v, "Code"); ok {
+		if _, ok := intField(v, "ExtendedCode"); ok {
+			return sqliteCodes[code&0xff]
+		}
+	}
+	return false
+}
+
+// intField returns the value of the named integer field of struct v.
+func intField(v reflect.Value, name string) (int64, bool) {
+	f := v.FieldByName(name)
+	if !f.IsValid() {
+		return 0, false
+	}
+	switch f.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return f.Int(), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return int64(f.Uint()), true
+	}
+	return 0, false
+}
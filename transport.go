@@ -0,0 +1,176 @@
+package errguard
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// Transport is an http.RoundTripper that uses a guard to retry
+// idempotent requests that fail with a transient network error
+// (see IsTransientNetworkError) or with status 429, 502, 503 or 504.
+// Other errors, such as TLS failures or an unsupported URL scheme,
+// are returned after the first attempt.
+//
+// A request is idempotent if its method is GET, HEAD, OPTIONS, TRACE,
+// PUT or DELETE, or if it has an Idempotency-Key header. A request
+// with a body is only retried if its GetBody function is set, which
+// is the case for requests created by http.NewRequest with common
+// body types.
+//
+// Failed attempts are marked with Retry, or with RetryAfter if the
+// response has a Retry-After header, so the guard's ShouldRetry
+// or Classifier should recognise those markers. The body of a
+// response with a retryable status is read and closed before
+// waiting, so the connection can be reused. When the guard gives
+// up after a retryable status, the last response is returned with
+// a copy of the first 64KB of its body.
+type Transport struct {
+	// Base is the transport used to make requests.
+	// If nil, http.DefaultTransport is used.
+	Base http.RoundTripper
+
+	// Guard determines the retry policy. If nil, a guard with
+	// the default settings and a limit of 4 attempts is used.
+	Guard *Guard
+}
+
+// RoundTrip implements the http.RoundTripper interface.
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	base := t.Base
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	hasBody := req.Body != nil && req.Body != http.NoBody
+	if !isIdempotent(req) || (hasBody && req.GetBody == nil) {
+		return base.RoundTrip(req)
+	}
+	guard := t.Guard
+	if guard == nil {
+		guard = &Guard{MaxAttempts: 4}
+	}
+	clock := guard.Clock
+	if clock == nil {
+		clock = systemClock{}
+	}
+
+	ctx := req.Context()
+	var resp *http.Response
+	var attempt int
+	err := guard.Run(ctx, func() error {
+		attempt++
+		// discard any response from the previous attempt,
+		// whose body has already been buffered and closed
+		resp = nil
+
+		r := req
+		if attempt > 1 && hasBody {
+			body, err := req.GetBody()
+			if err != nil {
+				return err
+			}
+			r = req.Clone(ctx)
+			r.Body = body
+		}
+
+		var err error
+		resp, err = base.RoundTrip(r)
+		if err != nil {
+			if ctx.Err() != nil || !IsTransientNetworkError(err, true) {
+				return err
+			}
+			return Retry(err)
+		}
+		if !isRetryableStatus(resp.StatusCode) {
+			return nil
+		}
+		bufferBody(resp)
+		statusErr := &statusError{resp: resp}
+		if d, ok := parseRetryAfter(resp.Header.Get("Retry-After"), clock.Now()); ok {
+			return RetryAfter(statusErr, d)
+		}
+		return Retry(statusErr)
+	})
+	if err != nil {
+		// resp is only set if the last attempt had a retryable status
+		if resp != nil && ctx.Err() == nil {
+			// gave up after a retryable status, so return the response
+			return resp, nil
+		}
+		return nil, err
+	}
+	return resp, nil
+}
+
+// statusError is the error for a response with a retryable status.
+type statusError struct {
+	resp *http.Response
+}
+
+func (e *statusError) Error() string {
+	return fmt.Sprintf("http status %s", e.resp.Status)
+}
+
+func isIdempotent(req *http.Request) bool {
+	switch req.Method {
+	case "", http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace, http.MethodPut, http.MethodDelete:
+		return true
+	}
+	return req.Header.Get("Idempotency-Key") != ""
+}
+
+func isRetryableStatus(code int) bool {
+	switch code {
+	case http.StatusTooManyRequests,
+		http.StatusBadGateway,
+		http.StatusServiceUnavailable,
+		http.StatusGatewayTimeout:
+		return true
+	}
+	return false
+}
+
+// parseRetryAfter parses the value of a Retry-After header, which
+// is either a number of seconds or an HTTP date.
+func parseRetryAfter(value string, now time.Time) (time.Duration, bool) {
+	if value == "" {
+		return 0, false
+	}
+	if seconds, err := strconv.Atoi(value); err == nil {
+		if seconds < 0 {
+			return 0, false
+		}
+		return time.Duration(seconds) * time.Second, true
+	}
+	if t, err := http.ParseTime(value); err == nil {
+		d := t.Sub(now)
+		if d < 0 {
+			d = 0
+		}
+		return d, true
+	}
+	return 0, false
+}
+
+// maxBufferedBody is the maximum size of the copy of a response
+// body kept in case the guard gives up.
+const maxBufferedBody = 64 << 10
+
+// bufferBody replaces the body of resp with a copy of up to
+// maxBufferedBody bytes, and closes the original body.
+func bufferBody(resp *http.Response) {
+	data, _ := io.ReadAll(io.LimitReader(resp.Body, maxBufferedBody))
+	drainBody(resp.Body)
+	resp.Body = io.NopCloser(bytes.NewReader(data))
+	resp.ContentLength = int64(len(data))
+}
+
+// drainBody reads a limited amount of the body before closing it,
+// so that the connection can be reused.
+func drainBody(body io.ReadCloser) {
+	io.CopyN(io.Discard, body, 4096)
+	body.Close()
+}
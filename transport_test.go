@@ -0,0 +1,316 @@
+package errguard
+
+import (
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/jjeffery/errguard/errguardtest"
+)
+
+// roundTripperFunc is an adapter to allow the use of an ordinary
+// function as an http.RoundTripper.
+type roundTripperFunc func(req *http.Request) (*http.Response, error)
+
+func (f roundTripperFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+func TestTransport(t *testing.T) {
+	var mutex sync.Mutex
+	var requests int
+	var bodies []string
+	var conns int
+	statuses := []int{}
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mutex.Lock()
+		defer mutex.Unlock()
+		body, _ := io.ReadAll(r.Body)
+		bodies = append(bodies, string(body))
+		status := http.StatusOK
+		if requests < len(statuses) {
+			status = statuses[requests]
+		}
+		requests++
+		if status == http.StatusTooManyRequests {
+			w.Header().Set("Retry-After", "0")
+		}
+		w.WriteHeader(status)
+		io.WriteString(w, strings.Repeat("x", 1000))
+	})
+	server := httptest.NewUnstartedServer(handler)
+	server.Config.ConnState = func(conn net.Conn, state http.ConnState) {
+		if state == http.StateNew {
+			mutex.Lock()
+			conns++
+			mutex.Unlock()
+		}
+	}
+	server.Start()
+	defer server.Close()
+
+	client := &http.Client{
+		Transport: &Transport{
+			Base: &http.Transport{},
+			Guard: &Guard{
+				Backoff:     ConstantBackoff{Interval: time.Millisecond},
+				MaxAttempts: 4,
+			},
+		},
+	}
+
+	tests := []struct {
+		name         string
+		method       string
+		body         string
+		header       http.Header
+		statuses     []int
+		wantStatus   int
+		wantRequests int
+	}{
+		{
+			name:         "get",
+			method:       http.MethodGet,
+			statuses:     []int{503, 502, 429},
+			wantStatus:   200,
+			wantRequests: 4,
+		},
+		{
+			name:         "put with body",
+			method:       http.MethodPut,
+			body:         "request body",
+			statuses:     []int{504},
+			wantStatus:   200,
+			wantRequests: 2,
+		},
+		{
+			name:         "post not retried",
+			method:       http.MethodPost,
+			body:         "request body",
+			statuses:     []int{503},
+			wantStatus:   503,
+			wantRequests: 1,
+		},
+		{
+			name:         "post with idempotency key",
+			method:       http.MethodPost,
+			body:         "request body",
+			header:       http.Header{"Idempotency-Key": {"abc"}},
+			statuses:     []int{503},
+			wantStatus:   200,
+			wantRequests: 2,
+		},
+		{
+			name:         "status not retried",
+			method:       http.MethodGet,
+			statuses:     []int{500},
+			wantStatus:   500,
+			wantRequests: 1,
+		},
+		{
+			name:         "give up",
+			method:       http.MethodGet,
+			statuses:     []int{503, 503, 503, 503, 503},
+			wantStatus:   503,
+			wantRequests: 4,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mutex.Lock()
+			requests, bodies, conns, statuses = 0, nil, 0, tt.statuses
+			mutex.Unlock()
+
+			req, err := http.NewRequest(tt.method, server.URL, strings.NewReader(tt.body))
+			if err != nil {
+				t.Fatal(err)
+			}
+			for k, v := range tt.header {
+				req.Header[k] = v
+			}
+			resp, err := client.Do(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			io.Copy(io.Discard, resp.Body)
+			resp.Body.Close()
+
+			mutex.Lock()
+			defer mutex.Unlock()
+			if got, want := resp.StatusCode, tt.wantStatus; got != want {
+				t.Errorf("status: got=%v, want=%v", got, want)
+			}
+			if got, want := requests, tt.wantRequests; got != want {
+				t.Errorf("requests: got=%v, want=%v", got, want)
+			}
+			for i, body := range bodies {
+				if got, want := body, tt.body; got != want {
+					t.Errorf("body %d: got=%q, want=%q", i, got, want)
+				}
+			}
+			if conns > 1 {
+				t.Errorf("conns: got=%v, want connection reused", conns)
+			}
+		})
+	}
+}
+
+// closeRecorder is a response body that records whether it is closed.
+type closeRecorder struct {
+	io.ReadCloser
+	closed bool
+}
+
+func (b *closeRecorder) Close() error {
+	b.closed = true
+	return b.ReadCloser.Close()
+}
+
+func TestTransportCloseBeforeWait(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Retry-After", "60")
+		w.WriteHeader(http.StatusServiceUnavailable)
+		io.WriteString(w, "try again later")
+	}))
+	defer server.Close()
+
+	var mutex sync.Mutex
+	var bodies []*closeRecorder
+	base := roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		resp, err := http.DefaultTransport.RoundTrip(req)
+		if err == nil {
+			mutex.Lock()
+			body := &closeRecorder{ReadCloser: resp.Body}
+			bodies = append(bodies, body)
+			resp.Body = body
+			mutex.Unlock()
+		}
+		return resp, err
+	})
+	clock := errguardtest.NewClock(time.Now())
+	client := &http.Client{
+		Transport: &Transport{
+			Base: base,
+			Guard: &Guard{
+				Clock:       clock,
+				MaxAttempts: 2,
+			},
+		},
+	}
+
+	done := make(chan *http.Response)
+	go func() {
+		resp, err := client.Get(server.URL)
+		if err != nil {
+			t.Error(err)
+		}
+		done <- resp
+	}()
+
+	// while the guard waits, the first response has been closed
+	clock.BlockUntil(1)
+	mutex.Lock()
+	if len(bodies) != 1 || !bodies[0].closed {
+		t.Errorf("want first response body closed before waiting")
+	}
+	mutex.Unlock()
+	clock.Advance(time.Minute)
+
+	// after giving up, the last response is returned with its body
+	resp := <-done
+	if resp == nil {
+		t.Fatal("want response")
+	}
+	defer resp.Body.Close()
+	if got, want := resp.StatusCode, http.StatusServiceUnavailable; got != want {
+		t.Errorf("status: got=%v, want=%v", got, want)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	if got, want := string(body), "try again later"; got != want {
+		t.Errorf("body: got=%q, want=%q", got, want)
+	}
+}
+
+func TestTransportConnectionError(t *testing.T) {
+	// find an address that refuses connections
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := listener.Addr().String()
+	listener.Close()
+
+	var attempts int
+	client := &http.Client{
+		Transport: &Transport{
+			Guard: &Guard{
+				Backoff:     ConstantBackoff{Interval: time.Millisecond},
+				MaxAttempts: 3,
+				Classifier: ClassifierFunc(func(err error) Decision {
+					attempts++
+					return ShouldRetryFunc(ShouldRetry).Classify(err)
+				}),
+			},
+		},
+	}
+	_, err = client.Get("http://" + addr)
+	if err == nil {
+		t.Fatal("want error, got nil")
+	}
+	if got, want := attempts, 3; got != want {
+		t.Errorf("attempts: got=%v, want=%v", got, want)
+	}
+}
+
+func TestTransportPermanentError(t *testing.T) {
+	var attempts int
+	client := &http.Client{
+		Transport: &Transport{
+			Guard: &Guard{
+				Backoff:     ConstantBackoff{Interval: time.Millisecond},
+				MaxAttempts: 5,
+				Hooks: &Hooks{
+					OnAttempt: func(attempt int) {
+						attempts++
+					},
+				},
+			},
+		},
+	}
+	_, err := client.Get("ftp://example.com/file")
+	if err == nil || !strings.Contains(err.Error(), "unsupported protocol scheme") {
+		t.Fatalf("got=%v, want unsupported protocol scheme", err)
+	}
+	if got, want := attempts, 1; got != want {
+		t.Errorf("attempts: got=%v, want=%v", got, want)
+	}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Date(2017, 1, 2, 3, 4, 5, 0, time.UTC)
+	tests := []struct {
+		value  string
+		want   time.Duration
+		wantOK bool
+	}{
+		{value: ""},
+		{value: "junk"},
+		{value: "-1"},
+		{value: "0", want: 0, wantOK: true},
+		{value: "120", want: 2 * time.Minute, wantOK: true},
+		{value: "Mon, 02 Jan 2017 03:04:35 GMT", want: 30 * time.Second, wantOK: true},
+		{value: "Mon, 02 Jan 2017 03:00:00 GMT", want: 0, wantOK: true},
+	}
+	for _, tt := range tests {
+		got, ok := parseRetryAfter(tt.value, now)
+		if got != tt.want || ok != tt.wantOK {
+			t.Errorf("%q: got=%v,%v, want=%v,%v", tt.value, got, ok, tt.want, tt.wantOK)
+		}
+	}
+}
@@ -0,0 +1,63 @@
+package errguard
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+)
+
+// RunTx runs function f in a database transaction using a guard
+// with the default settings, except that serialization failures
+// and deadlocks reported by the database driver are also retried
+// (see IsTransientSQLError). See Guard.RunTx.
+func RunTx(ctx context.Context, db *sql.DB, opts *sql.TxOptions, f func(tx *sql.Tx) error) error {
+	guard := Guard{ShouldRetry: shouldRetryTx}
+	return guard.do(ctx, func() error {
+		return runTx(ctx, db, opts, f)
+	})
+}
+
+// RunTx runs function f in a database transaction, and keeps
+// retrying the whole transaction while it fails with a retryable
+// error. Each attempt begins a new transaction, calls f and commits.
+// If f returns an error or panics, the transaction is rolled back.
+// If beginning or committing the transaction fails, the error is
+// treated the same way as an error returned by f.
+//
+// Serialization failures and deadlocks are usually reported by the
+// database driver, so the guard's ShouldRetry or Classifier will
+// need to recognise them. See IsTransientSQLError and the classify
+// package.
+func (g *Guard) RunTx(ctx context.Context, db *sql.DB, opts *sql.TxOptions, f func(tx *sql.Tx) error) error {
+	return g.do(ctx, func() error {
+		return runTx(ctx, db, opts, f)
+	})
+}
+
+// shouldRetryTx is the test used by the package-level RunTx.
+func shouldRetryTx(err error) bool {
+	return ShouldRetry(err) || IsTransientSQLError(err)
+}
+
+func runTx(ctx context.Context, db *sql.DB, opts *sql.TxOptions, f func(tx *sql.Tx) error) (err error) {
+	tx, err := db.BeginTx(ctx, opts)
+	if err != nil {
+		return err
+	}
+	committed := false
+	defer func() {
+		if committed {
+			return
+		}
+		// rollback on error, and also if f panics
+		if rbErr := tx.Rollback(); rbErr != nil && !errors.Is(rbErr, sql.ErrTxDone) && err != nil {
+			err = errors.Join(err, rbErr)
+		}
+	}()
+
+	if err := f(tx); err != nil {
+		return err
+	}
+	committed = true
+	return tx.Commit()
+}
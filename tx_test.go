@@ -0,0 +1,187 @@
+package errguard
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"testing"
+	"time"
+)
+
+// fakeDriver is an in-memory database/sql driver that records
+// transaction activity.
+type fakeDriver struct {
+	begins    int
+	commits   int
+	rollbacks int
+
+	// commitErrs are returned by successive calls to Commit
+	commitErrs []error
+}
+
+func (d *fakeDriver) Open(name string) (driver.Conn, error)            { return &fakeConn{d: d}, nil }
+func (d *fakeDriver) Connect(ctx context.Context) (driver.Conn, error) { return &fakeConn{d: d}, nil }
+func (d *fakeDriver) Driver() driver.Driver                            { return d }
+
+type fakeConn struct {
+	d *fakeDriver
+}
+
+func (c *fakeConn) Prepare(query string) (driver.Stmt, error) {
+	return nil, errors.New("fake driver does not support statements")
+}
+func (c *fakeConn) Close() error { return nil }
+func (c *fakeConn) Begin() (driver.Tx, error) {
+	c.d.begins++
+	return &fakeTx{d: c.d}, nil
+}
+
+type fakeTx struct {
+	d *fakeDriver
+}
+
+func (tx *fakeTx) Commit() error {
+	tx.d.commits++
+	if len(tx.d.commitErrs) > 0 {
+		err := tx.d.commitErrs[0]
+		tx.d.commitErrs = tx.d.commitErrs[1:]
+		return err
+	}
+	return nil
+}
+
+func (tx *fakeTx) Rollback() error {
+	tx.d.rollbacks++
+	return nil
+}
+
+func TestRunTx(t *testing.T) {
+	errConflict := errors.New("serialization failure")
+	errFatal := errors.New("constraint violation")
+	guard := Guard{
+		Backoff: ConstantBackoff{Interval: time.Millisecond},
+	}
+
+	tests := []struct {
+		name          string
+		fnErrs        []error
+		commitErrs    []error
+		wantErr       error
+		wantCalls     int
+		wantBegins    int
+		wantCommits   int
+		wantRollbacks int
+	}{
+		{
+			name:        "success",
+			fnErrs:      []error{nil},
+			wantCalls:   1,
+			wantBegins:  1,
+			wantCommits: 1,
+		},
+		{
+			name:          "retry work",
+			fnErrs:        []error{Retry(errConflict), Retry(errConflict), nil},
+			wantCalls:     3,
+			wantBegins:    3,
+			wantCommits:   1,
+			wantRollbacks: 2,
+		},
+		{
+			name:        "retry commit",
+			fnErrs:      []error{nil, nil},
+			commitErrs:  []error{Retry(errConflict)},
+			wantCalls:   2,
+			wantBegins:  2,
+			wantCommits: 2,
+		},
+		{
+			name:          "not retryable",
+			fnErrs:        []error{errFatal},
+			wantErr:       errFatal,
+			wantCalls:     1,
+			wantBegins:    1,
+			wantRollbacks: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := &fakeDriver{commitErrs: tt.commitErrs}
+			db := sql.OpenDB(d)
+			defer db.Close()
+
+			var calls int
+			err := guard.RunTx(context.Background(), db, nil, func(tx *sql.Tx) error {
+				err := tt.fnErrs[calls]
+				calls++
+				return err
+			})
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("err: got=%v, want=%v", err, tt.wantErr)
+			}
+			if got, want := calls, tt.wantCalls; got != want {
+				t.Errorf("calls: got=%v, want=%v", got, want)
+			}
+			if got, want := d.begins, tt.wantBegins; got != want {
+				t.Errorf("begins: got=%v, want=%v", got, want)
+			}
+			if got, want := d.commits, tt.wantCommits; got != want {
+				t.Errorf("commits: got=%v, want=%v", got, want)
+			}
+			if got, want := d.rollbacks, tt.wantRollbacks; got != want {
+				t.Errorf("rollbacks: got=%v, want=%v", got, want)
+			}
+		})
+	}
+}
+
+func TestRunTxPanic(t *testing.T) {
+	d := &fakeDriver{}
+	db := sql.OpenDB(d)
+	defer db.Close()
+
+	defer func() {
+		if r := recover(); r == nil {
+			t.Errorf("want panic")
+		}
+		if got, want := d.rollbacks, 1; got != want {
+			t.Errorf("rollbacks: got=%v, want=%v", got, want)
+		}
+	}()
+	RunTx(context.Background(), db, nil, func(tx *sql.Tx) error {
+		panic("test panic")
+	})
+}
+
+// sqlStateError is a driver error with a SQLSTATE code,
+// like those returned by github.com/lib/pq.
+type sqlStateError string
+
+func (e sqlStateError) Error() string    { return "sqlstate " + string(e) }
+func (e sqlStateError) SQLState() string { return string(e) }
+
+func TestRunTxDriverError(t *testing.T) {
+	d := &fakeDriver{commitErrs: []error{sqlStateError("40001")}}
+	db := sql.OpenDB(d)
+	defer db.Close()
+
+	var calls int
+	err := RunTx(context.Background(), db, nil, func(tx *sql.Tx) error {
+		calls++
+		if calls == 1 {
+			return sqlStateError("40P01")
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("got=%v, want nil", err)
+	}
+	if got, want := calls, 3; got != want {
+		t.Errorf("calls: got=%v, want=%v", got, want)
+	}
+	if got, want := d.commits, 2; got != want {
+		t.Errorf("commits: got=%v, want=%v", got, want)
+	}
+}